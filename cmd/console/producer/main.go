@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/riferrei/srclient"
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+	"github.com/urbanindo/go-kafka-http-sink/pkg/helper/logger"
+	"go.uber.org/zap"
+)
+
+// producer writes JSON records to a topic, serialized with the Confluent wire format,
+// to test the sink end to end.
+//
+// Usage:
+// ```
+// echo '{"type":"PL","user_id":2097886}' | go run ./cmd/console/producer -key-field user_id
+// go run ./cmd/console/producer -schema-file listing.avsc -input records.jsonl -header source=test
+// go run ./cmd/console/producer -raw -key abcd1234 < records.jsonl
+// ```
+func main() {
+	var (
+		topic       = flag.String("topic", "", "topic to produce to (default KAFKA_TOPIC)")
+		subject     = flag.String("subject", "", "schema registry subject (default <topic>-value)")
+		schemaFile  = flag.String("schema-file", "", "register this .avsc, .json or .proto schema under the subject before producing")
+		messageName = flag.String("message", "", "protobuf message name (default first message in the schema)")
+		input       = flag.String("input", "", "file with JSON records, one per line (default stdin)")
+		raw         = flag.Bool("raw", false, "produce the JSON records as is, without schema registry")
+		key         = flag.String("key", "", "message key for every record")
+		keyField    = flag.String("key-field", "", "JSON field of the record to use as message key")
+		headers     headerFlags
+	)
+	flag.Var(&headers, "header", "message header in key=value format, can be repeated")
+	flag.Parse()
+
+	logr := logger.Named("go_kafka_http_sink_producer")
+	ctx, stop := signal.NotifyContext(
+		context.Background(), syscall.SIGINT, syscall.SIGTERM,
+	)
+	defer stop()
+
+	// only the Kafka settings are loaded, the producer needs none of the sink settings
+	conf, err := config.LoadKafka(os.Getenv(config.FileEnv))
+	if err != nil {
+		logr.Fatal("invalid kafka config", zap.Error(err))
+	}
+	if *topic == "" {
+		*topic = conf.Topic
+	}
+	if *topic == "" {
+		logr.Fatal("-topic or KAFKA_TOPIC is required")
+	}
+	if *subject == "" {
+		*subject = *topic + "-value"
+	}
+
+	var ser serializer = rawSerializer{}
+	if !*raw {
+		if conf.SchemaRegistryUrl == nil {
+			logr.Fatal("KAFKA_SCHEMA_REGISTRY_URL is required unless -raw is set")
+		}
+
+		schema, err := resolveSchema(srclient.NewSchemaRegistryClient(*conf.SchemaRegistryUrl), *subject, *schemaFile)
+		if err != nil {
+			logr.Fatal("failed to resolve schema", zap.Error(err))
+		}
+
+		ser, err = newSerializer(schema, *messageName)
+		if err != nil {
+			logr.Fatal("failed to initiate serializer", zap.Error(err))
+		}
+		logr.Info("using schema", zap.String("subject", *subject), zap.Int("schema_id", schema.ID()))
+	}
+
+	var reader io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			logr.Fatal("failed to open input", zap.Error(err))
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	transport, err := kafkaclient.NewTransport(*conf)
+	if err != nil {
+		logr.Fatal("failed to initiate kafka transport", zap.Error(err))
+	}
+	p := kafkaclient.NewWriter(*conf, *topic, transport)
+	defer p.Close()
+
+	produced := 0
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		record := []byte(strings.TrimSpace(scanner.Text()))
+		if len(record) == 0 {
+			continue
+		}
+
+		msg, err := newMessage(ser, record, *key, *keyField, headers)
+		if err != nil {
+			logr.Fatal("failed to build message", zap.Int("line", line), zap.Error(err))
+		}
+
+		if err := p.WriteMessages(ctx, msg); err != nil {
+			logr.Fatal("failed to produce message", zap.Int("line", line), zap.Error(err))
+		}
+		produced++
+	}
+	if err := scanner.Err(); err != nil {
+		logr.Fatal("failed to read input", zap.Error(err))
+	}
+
+	logr.Info("produced messages", zap.String("topic", *topic), zap.Int("count", produced))
+}
+
+// resolveSchema registers the schema file under the subject when given,
+// otherwise it uses the latest registered version of the subject.
+func resolveSchema(sr *srclient.SchemaRegistryClient, subject, schemaFile string) (*srclient.Schema, error) {
+	if schemaFile == "" {
+		return sr.GetLatestSchema(subject)
+	}
+
+	content, err := os.ReadFile(schemaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaType := srclient.Avro
+	switch filepath.Ext(schemaFile) {
+	case ".proto":
+		schemaType = srclient.Protobuf
+	case ".json":
+		schemaType = srclient.Json
+	}
+
+	return sr.CreateSchema(subject, string(content), schemaType)
+}
+
+func newMessage(ser serializer, record []byte, key, keyField string, headers headerFlags) (kafka.Message, error) {
+	value, err := ser.Serialize(record)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	msg := kafka.Message{
+		Key:     []byte(key),
+		Value:   value,
+		Headers: headers.kafkaHeaders(),
+	}
+
+	if keyField != "" {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(record, &fields); err != nil {
+			return kafka.Message{}, fmt.Errorf("invalid JSON record: %w", err)
+		}
+
+		field, ok := fields[keyField]
+		if !ok {
+			return kafka.Message{}, fmt.Errorf("key field %q not found in record", keyField)
+		}
+
+		var str string
+		if err := json.Unmarshal(field, &str); err == nil {
+			msg.Key = []byte(str)
+		} else {
+			msg.Key = field
+		}
+	}
+
+	return msg, nil
+}
+
+type headerFlags []kafka.Header
+
+func (h *headerFlags) String() string {
+	pairs := make([]string, 0, len(*h))
+	for _, header := range *h {
+		pairs = append(pairs, header.Key+"="+string(header.Value))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (h *headerFlags) Set(value string) error {
+	idx := strings.Index(value, "=")
+	if idx <= 0 {
+		return fmt.Errorf("header %q should be in key=value format", value)
+	}
+
+	*h = append(*h, kafka.Header{Key: value[:idx], Value: []byte(value[idx+1:])})
+	return nil
+}
+
+func (h headerFlags) kafkaHeaders() []kafka.Header {
+	return append([]kafka.Header{}, h...)
+}
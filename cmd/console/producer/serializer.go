@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/linkedin/goavro/v2"
+	"github.com/riferrei/srclient"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// serializer converts a JSON record into the message value.
+type serializer interface {
+	Serialize(record []byte) ([]byte, error)
+}
+
+// newSerializer creates the Confluent wire format serializer for the registered schema.
+// messageName selects the Protobuf message, the first message of the schema is used when empty.
+func newSerializer(schema *srclient.Schema, messageName string) (serializer, error) {
+	schemaType := srclient.Avro
+	if schema.SchemaType() != nil {
+		schemaType = *schema.SchemaType()
+	}
+
+	switch schemaType {
+	case srclient.Avro:
+		codec, err := goavro.NewCodecForStandardJSONFull(schema.Schema())
+		if err != nil {
+			return nil, fmt.Errorf("error initiate new avro codec: %w", err)
+		}
+		return &avroSerializer{schemaID: schema.ID(), codec: codec}, nil
+	case srclient.Json:
+		if schema.JsonSchema() == nil {
+			return nil, fmt.Errorf("invalid JSON schema with id '%d'", schema.ID())
+		}
+		return &jsonSchemaSerializer{schemaID: schema.ID(), schema: schema}, nil
+	case srclient.Protobuf:
+		descriptor, indexes, err := compileProtobuf(schema.Schema(), messageName)
+		if err != nil {
+			return nil, err
+		}
+		return &protobufSerializer{schemaID: schema.ID(), descriptor: descriptor, indexes: indexes}, nil
+	default:
+		return nil, fmt.Errorf("unsupported schema type %s", schemaType)
+	}
+}
+
+type rawSerializer struct{}
+
+func (rawSerializer) Serialize(record []byte) ([]byte, error) {
+	return record, nil
+}
+
+type avroSerializer struct {
+	schemaID int
+	codec    *goavro.Codec
+}
+
+func (s *avroSerializer) Serialize(record []byte) ([]byte, error) {
+	native, _, err := s.codec.NativeFromTextual(record)
+	if err != nil {
+		return nil, fmt.Errorf("error decode native from textual: %w", err)
+	}
+
+	value, err := s.codec.BinaryFromNative(nil, native)
+	if err != nil {
+		return nil, fmt.Errorf("error encode binary from native: %w", err)
+	}
+
+	return append(wireHeader(s.schemaID), value...), nil
+}
+
+type jsonSchemaSerializer struct {
+	schemaID int
+	schema   *srclient.Schema
+}
+
+func (s *jsonSchemaSerializer) Serialize(record []byte) ([]byte, error) {
+	var doc interface{}
+	if err := json.Unmarshal(record, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON record: %w", err)
+	}
+
+	if err := s.schema.JsonSchema().Validate(doc); err != nil {
+		return nil, fmt.Errorf("record does not match JSON schema: %w", err)
+	}
+
+	return append(wireHeader(s.schemaID), record...), nil
+}
+
+type protobufSerializer struct {
+	schemaID   int
+	descriptor protoreflect.MessageDescriptor
+	indexes    []int
+}
+
+func (s *protobufSerializer) Serialize(record []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(s.descriptor)
+	if err := protojson.Unmarshal(record, msg); err != nil {
+		return nil, fmt.Errorf("record does not match protobuf message %s: %w", s.descriptor.FullName(), err)
+	}
+
+	value, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("error encode protobuf message: %w", err)
+	}
+
+	header := append(wireHeader(s.schemaID), messageIndexes(s.indexes)...)
+	return append(header, value...), nil
+}
+
+// wireHeader is the Confluent wire format prefix: magic byte 0 and the big endian schema ID.
+func wireHeader(schemaID int) []byte {
+	header := make([]byte, 5)
+	binary.BigEndian.PutUint32(header[1:], uint32(schemaID))
+	return header
+}
+
+// messageIndexes encodes the path to the message in the Protobuf schema as zigzag varints,
+// the common case of the first top-level message is encoded as a single 0.
+func messageIndexes(indexes []int) []byte {
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return []byte{0}
+	}
+
+	buf := binary.AppendVarint(nil, int64(len(indexes)))
+	for _, index := range indexes {
+		buf = binary.AppendVarint(buf, int64(index))
+	}
+	return buf
+}
+
+// compileProtobuf compiles the Protobuf schema text and finds the message to serialize.
+// Imports of other registry subjects are not supported, only the well-known types.
+func compileProtobuf(schema, messageName string) (protoreflect.MessageDescriptor, []int, error) {
+	const filename = "schema.proto"
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{filename: schema}),
+		}),
+	}
+
+	files, err := compiler.Compile(context.Background(), filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compile protobuf schema: %w", err)
+	}
+
+	messages := files[0].Messages()
+	if messages.Len() == 0 {
+		return nil, nil, fmt.Errorf("protobuf schema has no message")
+	}
+	if messageName == "" {
+		return messages.Get(0), []int{0}, nil
+	}
+
+	if descriptor, indexes := findMessage(messages, protoreflect.FullName(messageName), nil); descriptor != nil {
+		return descriptor, indexes, nil
+	}
+	return nil, nil, fmt.Errorf("message %s not found in protobuf schema", messageName)
+}
+
+func findMessage(messages protoreflect.MessageDescriptors, name protoreflect.FullName, path []int) (protoreflect.MessageDescriptor, []int) {
+	for i := 0; i < messages.Len(); i++ {
+		msg := messages.Get(i)
+		msgPath := append(append([]int{}, path...), i)
+		if msg.FullName() == name || msg.Name() == protoreflect.Name(name) {
+			return msg, msgPath
+		}
+		if found, foundPath := findMessage(msg.Messages(), name, msgPath); found != nil {
+			return found, foundPath
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestWireHeader(t *testing.T) {
+	tests := []struct {
+		schemaID int
+		want     []byte
+	}{
+		{schemaID: 1, want: []byte{0, 0, 0, 0, 1}},
+		{schemaID: 258, want: []byte{0, 0, 0, 1, 2}},
+		{schemaID: 1<<24 + 5, want: []byte{0, 1, 0, 0, 5}},
+	}
+
+	for _, tt := range tests {
+		if got := wireHeader(tt.schemaID); !bytes.Equal(got, tt.want) {
+			t.Errorf("wireHeader(%d) = %v, want %v", tt.schemaID, got, tt.want)
+		}
+	}
+}
+
+func TestMessageIndexes(t *testing.T) {
+	tests := []struct {
+		name    string
+		indexes []int
+		want    []byte
+	}{
+		{name: "first message", indexes: []int{0}, want: []byte{0}},
+		{name: "second message", indexes: []int{1}, want: []byte{2, 2}},
+		{name: "nested message", indexes: []int{0, 2}, want: []byte{4, 0, 4}},
+		{name: "large index", indexes: []int{70}, want: []byte{2, 140, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messageIndexes(tt.indexes); !bytes.Equal(got, tt.want) {
+				t.Errorf("messageIndexes(%v) = %v, want %v", tt.indexes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileProtobuf(t *testing.T) {
+	schema := `
+syntax = "proto3";
+package shop.v1;
+
+import "google/protobuf/timestamp.proto";
+
+message Order {
+  string id = 1;
+  google.protobuf.Timestamp created_at = 2;
+  message Item {
+    string sku = 1;
+  }
+  repeated Item items = 3;
+}
+
+message Payment {
+  string order_id = 1;
+}
+`
+
+	tests := []struct {
+		name        string
+		schema      string
+		messageName string
+		wantName    protoreflect.FullName
+		wantIndexes []int
+		wantErr     bool
+	}{
+		{
+			name:        "first message by default",
+			schema:      schema,
+			wantName:    "shop.v1.Order",
+			wantIndexes: []int{0},
+		},
+		{
+			name:        "message by name",
+			schema:      schema,
+			messageName: "Payment",
+			wantName:    "shop.v1.Payment",
+			wantIndexes: []int{1},
+		},
+		{
+			name:        "nested message by full name",
+			schema:      schema,
+			messageName: "shop.v1.Order.Item",
+			wantName:    "shop.v1.Order.Item",
+			wantIndexes: []int{0, 0},
+		},
+		{
+			name:        "unknown message",
+			schema:      schema,
+			messageName: "Refund",
+			wantErr:     true,
+		},
+		{
+			name:    "invalid schema",
+			schema:  `syntax = "proto3"; message Order { string id = ; }`,
+			wantErr: true,
+		},
+		{
+			name:    "schema without message",
+			schema:  `syntax = "proto3"; enum Status { UNKNOWN = 0; }`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			descriptor, indexes, err := compileProtobuf(tt.schema, tt.messageName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compileProtobuf() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if descriptor.FullName() != tt.wantName {
+				t.Errorf("compileProtobuf() message = %s, want %s", descriptor.FullName(), tt.wantName)
+			}
+			if !reflect.DeepEqual(indexes, tt.wantIndexes) {
+				t.Errorf("compileProtobuf() indexes = %v, want %v", indexes, tt.wantIndexes)
+			}
+		})
+	}
+}
+
+func TestProtobufSerializer(t *testing.T) {
+	descriptor, indexes, err := compileProtobuf(`syntax = "proto3"; message Order { string id = 1; }`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser := &protobufSerializer{schemaID: 7, descriptor: descriptor, indexes: indexes}
+
+	got, err := ser.Serialize([]byte(`{"id":"a"}`))
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	// wire header, message index 0, then field 1 as a length delimited "a"
+	want := []byte{0, 0, 0, 0, 7, 0, 0x0a, 1, 'a'}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Serialize() = %v, want %v", got, want)
+	}
+
+	if _, err := ser.Serialize([]byte(`{"unknown":1}`)); err == nil {
+		t.Errorf("Serialize() error = nil for a record that does not match the message")
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+	"github.com/urbanindo/go-kafka-http-sink/internal/processor"
+	"github.com/urbanindo/go-kafka-http-sink/pkg/helper/logger"
+	"go.uber.org/zap"
+)
+
+// replay re-drives messages from the error topic through the same processor as the worker.
+//
+// Usage:
+// ```
+// go run ./cmd/console/replay -response-code 5xx -since 2024-01-01T00:00:00Z -dry-run
+// go run ./cmd/console/replay -pipeline orders -partition 0 -from-offset 100 -to-offset 200
+// ```
+func main() {
+	var (
+		pipeline      = flag.String("pipeline", "", "pipeline whose error topic and sink are used, required when several are configured")
+		topic         = flag.String("topic", "", "error topic to replay from (default KAFKA_ERROR_TOPIC of the pipeline)")
+		partition     = flag.Int("partition", -1, "only replay this partition (default all partitions)")
+		fromOffset    = flag.Int64("from-offset", -1, "first error topic offset to replay, inclusive")
+		toOffset      = flag.Int64("to-offset", -1, "last error topic offset to replay, exclusive")
+		since         = flag.String("since", "", "only replay error records written at or after this RFC3339 time")
+		until         = flag.String("until", "", "only replay error records written before this RFC3339 time")
+		responseCodes = flag.String("response-code", "", "comma separated response codes to replay, e.g. 500,502 or 5xx")
+		errorClasses  = flag.String("error-class", "", "comma separated error classes to replay: decode, request, transport, http, grpc, response")
+		dryRun        = flag.Bool("dry-run", false, "print the requests of the matched records without delivering them")
+		idleTimeout   = flag.Duration("idle-timeout", 10*time.Second, "stop reading a partition when no record is read within this time")
+		writeResults  = flag.Bool("write-results", false, "write replay results to the configured success and error topics")
+	)
+	flag.Parse()
+
+	logr := logger.Named("go_kafka_http_sink_replay")
+	ctx, stop := signal.NotifyContext(
+		context.Background(), syscall.SIGINT, syscall.SIGTERM,
+	)
+	defer stop()
+
+	loaded, err := config.Load(os.Getenv(config.FileEnv))
+	if err != nil {
+		logr.Fatal("invalid config", zap.Error(err))
+	}
+	selected, err := selectPipeline(loaded, *pipeline)
+	if err != nil {
+		logr.Fatal("invalid -pipeline", zap.Error(err))
+	}
+	conf := &selected.Config
+
+	if *topic == "" {
+		if conf.KafkaConfig.ErrorTopic == nil {
+			logr.Fatal("-topic or KAFKA_ERROR_TOPIC is required")
+		}
+		*topic = *conf.KafkaConfig.ErrorTopic
+	}
+
+	if *idleTimeout <= 0 {
+		logr.Fatal("-idle-timeout must be positive")
+	}
+
+	filter, err := newReplayFilter(*since, *until, *responseCodes, *errorClasses)
+	if err != nil {
+		logr.Fatal("invalid filter", zap.Error(err))
+	}
+
+	opts := replayOptions{
+		pipeline:     selected.Name,
+		topic:        *topic,
+		partition:    *partition,
+		fromOffset:   *fromOffset,
+		toOffset:     *toOffset,
+		filter:       filter,
+		dryRun:       *dryRun,
+		idleTimeout:  *idleTimeout,
+		writeResults: *writeResults,
+	}
+	report := &replayReport{}
+	err = replay(ctx, logr, conf, opts, report)
+	printReport(report)
+	if err != nil {
+		if ctx.Err() != nil {
+			logr.Warn("replay interrupted")
+			return
+		}
+		logr.Error("replay failed", zap.Error(err))
+		os.Exit(1)
+	}
+}
+
+// selectPipeline returns the named pipeline with its resolved config, the name can
+// only be omitted when a single pipeline is configured.
+func selectPipeline(conf *config.Config, name string) (*config.PipelineConfig, error) {
+	pipelines := conf.AllPipelines()
+	if name == "" && len(pipelines) == 1 {
+		return &pipelines[0], nil
+	}
+
+	var names []string
+	for i := range pipelines {
+		if pipelines[i].Name == name {
+			return &pipelines[i], nil
+		}
+		names = append(names, pipelines[i].Name)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("required when several pipelines are configured: %s", strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("unknown pipeline %q, configured pipelines: %s", name, strings.Join(names, ", "))
+}
+
+type replayOptions struct {
+	pipeline     string
+	topic        string
+	partition    int
+	fromOffset   int64
+	toOffset     int64
+	filter       *replayFilter
+	dryRun       bool
+	idleTimeout  time.Duration
+	writeResults bool
+}
+
+// replay replays the matched records of every selected partition. It returns instead of
+// exiting on errors, so the writers are closed and the queued results are written.
+func replay(ctx context.Context, logr *zap.Logger, conf *config.Config, opts replayOptions, report *replayReport) error {
+	dialer, err := kafkaclient.NewDialer(conf.KafkaConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initiate kafka dialer: %w", err)
+	}
+
+	var (
+		eWriter kafkaclient.Producer
+		sWriter kafkaclient.Producer
+	)
+	if opts.writeResults && !opts.dryRun {
+		transport, err := kafkaclient.NewTransport(conf.KafkaConfig)
+		if err != nil {
+			return fmt.Errorf("failed to initiate kafka transport: %w", err)
+		}
+		if conf.KafkaConfig.SuccessTopic != nil {
+			sWriter = kafkaclient.NewWriter(conf.KafkaConfig, *conf.KafkaConfig.SuccessTopic, transport)
+			defer sWriter.Close()
+		}
+		if conf.KafkaConfig.ErrorTopic != nil {
+			eWriter = kafkaclient.NewWriter(conf.KafkaConfig, *conf.KafkaConfig.ErrorTopic, transport)
+			defer eWriter.Close()
+		}
+	}
+	proc := processor.NewProcessor(opts.pipeline, conf, logr, eWriter, sWriter)
+	defer proc.Close()
+
+	offsets, err := kafkaclient.ReadPartitionOffsets(ctx, conf.KafkaConfig, dialer, opts.topic)
+	if err != nil {
+		return fmt.Errorf("failed to read error topic offsets: %w", err)
+	}
+
+	for _, partitionOffsets := range offsets {
+		if opts.partition >= 0 && partitionOffsets.Partition != opts.partition {
+			continue
+		}
+
+		start, end := partitionOffsets.First, partitionOffsets.Last
+		if opts.fromOffset > start {
+			start = opts.fromOffset
+		}
+		if opts.toOffset >= 0 && opts.toOffset < end {
+			end = opts.toOffset
+		}
+		if opts.filter.since != nil {
+			offset, err := kafkaclient.ReadOffsetAt(ctx, conf.KafkaConfig, dialer, opts.topic, partitionOffsets.Partition, *opts.filter.since)
+			if err != nil {
+				return fmt.Errorf("failed to read offset by time: %w", err)
+			}
+			if offset > start {
+				start = offset
+			}
+		}
+		if opts.filter.until != nil {
+			offset, err := kafkaclient.ReadOffsetAt(ctx, conf.KafkaConfig, dialer, opts.topic, partitionOffsets.Partition, *opts.filter.until)
+			if err != nil {
+				return fmt.Errorf("failed to read offset by time: %w", err)
+			}
+			if offset < end {
+				end = offset
+			}
+		}
+		if start >= end {
+			continue
+		}
+
+		logr.Info(
+			"replaying partition",
+			zap.Int("partition", partitionOffsets.Partition),
+			zap.Int64("from_offset", start),
+			zap.Int64("to_offset", end),
+		)
+
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   kafkaclient.Brokers(conf.KafkaConfig),
+			Topic:     opts.topic,
+			Partition: partitionOffsets.Partition,
+			Dialer:    dialer,
+		})
+		if err := reader.SetOffset(start); err != nil {
+			reader.Close()
+			return fmt.Errorf("failed to set offset: %w", err)
+		}
+
+		err := readUntil(ctx, reader, end, opts.idleTimeout, func(record kafka.Message) {
+			replayRecord(ctx, logr, proc, opts.filter, record, opts.dryRun, report)
+		})
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read error topic: %w", err)
+		}
+	}
+
+	return nil
+}
+
+type recordReader interface {
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+	Lag() int64
+}
+
+// readUntil calls fn with every record before the end offset. It also stops when the
+// partition has no more records or when none is read within idle, since the offset
+// before end may not exist, e.g. removed by compaction or a transaction marker.
+func readUntil(ctx context.Context, reader recordReader, end int64, idle time.Duration, fn func(kafka.Message)) error {
+	for {
+		readCtx, cancel := context.WithTimeout(ctx, idle)
+		record, err := reader.ReadMessage(readCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(readCtx.Err(), context.DeadlineExceeded) {
+				return nil
+			}
+			return err
+		}
+
+		if record.Offset >= end {
+			return nil
+		}
+		fn(record)
+		if record.Offset >= end-1 || reader.Lag() == 0 {
+			return nil
+		}
+	}
+}
+
+type messageProcessor interface {
+	Process(ctx context.Context, msg kafka.Message) error
+	Inspect(msg kafka.Message) *processor.Inspection
+}
+
+// replayInspection is the dry-run output, the request the error record would be replayed with.
+type replayInspection struct {
+	ErrorPartition int   `json:"error_partition"`
+	ErrorOffset    int64 `json:"error_offset"`
+	*processor.Inspection
+}
+
+func replayRecord(ctx context.Context, logr *zap.Logger, proc messageProcessor, filter *replayFilter, record kafka.Message, dryRun bool, report *replayReport) {
+	report.Scanned++
+
+	var payload processor.ErrorPayload
+	if err := json.Unmarshal(record.Value, &payload); err != nil {
+		report.Invalid++
+		logr.Warn("skipping invalid error record", zap.Int64("offset", record.Offset), zap.Error(err))
+		return
+	}
+
+	if !filter.match(record, &payload) {
+		return
+	}
+	report.Matched++
+
+	msg, err := payload.OriginalMessage(record.Key)
+	if err != nil {
+		report.Invalid++
+		logr.Warn("skipping error record without original message", zap.Int64("offset", record.Offset), zap.Error(err))
+		return
+	}
+
+	if dryRun {
+		line, _ := json.Marshal(replayInspection{
+			ErrorPartition: record.Partition,
+			ErrorOffset:    record.Offset,
+			Inspection:     proc.Inspect(msg),
+		})
+		fmt.Println(string(line))
+		return
+	}
+
+	if err := proc.Process(ctx, msg); err != nil {
+		report.Failed++
+		logr.Error(
+			"failed to replay message",
+			zap.Int64("error_offset", record.Offset),
+			zap.String("topic", msg.Topic),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(err),
+		)
+		return
+	}
+	report.Delivered++
+}
+
+type replayFilter struct {
+	since         *time.Time
+	until         *time.Time
+	responseCodes []string
+	errorClasses  map[string]bool
+}
+
+func newReplayFilter(since, until, responseCodes, errorClasses string) (*replayFilter, error) {
+	filter := &replayFilter{errorClasses: map[string]bool{}}
+
+	if since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -since: %w", err)
+		}
+		filter.since = &t
+	}
+
+	if until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -until: %w", err)
+		}
+		filter.until = &t
+	}
+
+	for _, code := range splitList(responseCodes) {
+		code = strings.ToLower(code)
+		if _, err := strconv.Atoi(strings.ReplaceAll(code, "x", "0")); err != nil || len(code) != 3 {
+			return nil, fmt.Errorf("invalid -response-code %q", code)
+		}
+		filter.responseCodes = append(filter.responseCodes, code)
+	}
+
+	for _, class := range splitList(errorClasses) {
+		filter.errorClasses[class] = true
+	}
+
+	return filter, nil
+}
+
+// match checks the record against every configured filter, a response code
+// pattern like 5xx matches any code in the 500 range.
+func (f *replayFilter) match(record kafka.Message, payload *processor.ErrorPayload) bool {
+	if f.since != nil && record.Time.Before(*f.since) {
+		return false
+	}
+	if f.until != nil && !record.Time.Before(*f.until) {
+		return false
+	}
+	if len(f.errorClasses) > 0 && !f.errorClasses[payload.ErrorClass] {
+		return false
+	}
+	if len(f.responseCodes) == 0 {
+		return true
+	}
+
+	code := strconv.Itoa(payload.ResponseCode)
+	for _, pattern := range f.responseCodes {
+		if matchCode(pattern, code) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchCode(pattern, code string) bool {
+	if len(pattern) != len(code) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != 'x' && pattern[i] != code[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+type replayReport struct {
+	Scanned   int `json:"scanned"`
+	Matched   int `json:"matched"`
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+	Invalid   int `json:"invalid"`
+}
+
+func printReport(report *replayReport) {
+	summary, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Fprintln(os.Stderr, string(summary))
+}
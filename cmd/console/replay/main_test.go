@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/processor"
+	"go.uber.org/zap"
+)
+
+func TestSelectPipeline(t *testing.T) {
+	single := &config.Config{HttpApiUrl: "http://api/v1/orders"}
+	several := &config.Config{
+		HttpApiUrl: "http://api/top-level",
+		Pipelines: []config.PipelineConfig{
+			{Name: "orders", Config: config.Config{HttpApiUrl: "http://api/v1/orders"}},
+			{Name: "payments", Config: config.Config{HttpApiUrl: "http://api/v1/payments"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		conf     *config.Config
+		pipeline string
+		wantURL  string
+		wantErr  bool
+	}{
+		{name: "top level without name", conf: single, wantURL: "http://api/v1/orders"},
+		{name: "top level by default name", conf: single, pipeline: config.DefaultPipeline, wantURL: "http://api/v1/orders"},
+		{name: "pipeline by name", conf: several, pipeline: "payments", wantURL: "http://api/v1/payments"},
+		{name: "name required with several pipelines", conf: several, wantErr: true},
+		{name: "unknown pipeline", conf: several, pipeline: "refunds", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectPipeline(tt.conf, tt.pipeline)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectPipeline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Config.HttpApiUrl != tt.wantURL {
+				t.Errorf("selectPipeline() HttpApiUrl = %s, want %s", got.Config.HttpApiUrl, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestNewReplayFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		since         string
+		until         string
+		responseCodes string
+		errorClasses  string
+		wantErr       bool
+	}{
+		{name: "no filter"},
+		{name: "every filter", since: "2024-01-01T00:00:00Z", until: "2024-01-02T00:00:00Z", responseCodes: "500, 5XX", errorClasses: "http,transport"},
+		{name: "invalid since", since: "2024-01-01", wantErr: true},
+		{name: "invalid until", until: "yesterday", wantErr: true},
+		{name: "invalid response code", responseCodes: "50x0", wantErr: true},
+		{name: "non numeric response code", responseCodes: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newReplayFilter(tt.since, tt.until, tt.responseCodes, tt.errorClasses)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newReplayFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReplayFilterMatch(t *testing.T) {
+	written := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		since         string
+		until         string
+		responseCodes string
+		errorClasses  string
+		payload       processor.ErrorPayload
+		want          bool
+	}{
+		{
+			name:    "no filter",
+			payload: processor.ErrorPayload{ErrorClass: processor.ErrorClassDecode},
+			want:    true,
+		},
+		{
+			name:          "response code pattern",
+			responseCodes: "5xx",
+			payload:       processor.ErrorPayload{ErrorClass: processor.ErrorClassHTTP, ResponseCode: 503},
+			want:          true,
+		},
+		{
+			name:          "response code outside the pattern",
+			responseCodes: "5xx",
+			payload:       processor.ErrorPayload{ErrorClass: processor.ErrorClassHTTP, ResponseCode: 429},
+		},
+		{
+			name:          "any of the response codes",
+			responseCodes: "429,502",
+			payload:       processor.ErrorPayload{ErrorClass: processor.ErrorClassHTTP, ResponseCode: 429},
+			want:          true,
+		},
+		{
+			name:          "response code without response",
+			responseCodes: "5xx",
+			payload:       processor.ErrorPayload{ErrorClass: processor.ErrorClassTransport},
+		},
+		{
+			name:         "error class",
+			errorClasses: "transport,response",
+			payload:      processor.ErrorPayload{ErrorClass: processor.ErrorClassTransport},
+			want:         true,
+		},
+		{
+			name:         "other error class",
+			errorClasses: "transport",
+			payload:      processor.ErrorPayload{ErrorClass: processor.ErrorClassHTTP, ResponseCode: 500},
+		},
+		{
+			name:    "since is inclusive",
+			since:   "2024-01-01T12:00:00Z",
+			payload: processor.ErrorPayload{ErrorClass: processor.ErrorClassHTTP},
+			want:    true,
+		},
+		{
+			name:    "before since",
+			since:   "2024-01-01T12:00:01Z",
+			payload: processor.ErrorPayload{ErrorClass: processor.ErrorClassHTTP},
+		},
+		{
+			name:    "until is exclusive",
+			until:   "2024-01-01T12:00:00Z",
+			payload: processor.ErrorPayload{ErrorClass: processor.ErrorClassHTTP},
+		},
+		{
+			name:    "before until",
+			until:   "2024-01-01T13:00:00Z",
+			payload: processor.ErrorPayload{ErrorClass: processor.ErrorClassHTTP},
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newReplayFilter(tt.since, tt.until, tt.responseCodes, tt.errorClasses)
+			if err != nil {
+				t.Fatalf("newReplayFilter() error = %v", err)
+			}
+
+			if got := filter.match(kafka.Message{Time: written}, &tt.payload); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeReader struct {
+	records []kafka.Message
+	// last is the offset of the last record in the partition, used for the lag
+	last int64
+	read int
+	err  error
+}
+
+func (f *fakeReader) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	if f.read >= len(f.records) {
+		if f.err != nil {
+			return kafka.Message{}, f.err
+		}
+		<-ctx.Done()
+		return kafka.Message{}, ctx.Err()
+	}
+	f.read++
+	return f.records[f.read-1], nil
+}
+
+func (f *fakeReader) Lag() int64 {
+	if f.read == 0 {
+		return -1
+	}
+	return f.last - f.records[f.read-1].Offset
+}
+
+func TestReadUntil(t *testing.T) {
+	records := func(offsets ...int64) []kafka.Message {
+		var msgs []kafka.Message
+		for _, offset := range offsets {
+			msgs = append(msgs, kafka.Message{Offset: offset})
+		}
+		return msgs
+	}
+
+	tests := []struct {
+		name    string
+		reader  *fakeReader
+		end     int64
+		want    []int64
+		wantErr bool
+	}{
+		{
+			name:   "stops at the end offset",
+			reader: &fakeReader{records: records(2, 3, 4, 5), last: 5},
+			end:    4,
+			want:   []int64{2, 3},
+		},
+		{
+			name:   "skips the record after a missing end offset",
+			reader: &fakeReader{records: records(2, 5, 6), last: 6},
+			end:    4,
+			want:   []int64{2},
+		},
+		{
+			name:   "stops at the last record of the partition",
+			reader: &fakeReader{records: records(2, 3), last: 3},
+			end:    10,
+			want:   []int64{2, 3},
+		},
+		{
+			name:   "stops when no record is read",
+			reader: &fakeReader{records: records(2), last: 5},
+			end:    10,
+			want:   []int64{2},
+		},
+		{
+			name:    "read error",
+			reader:  &fakeReader{records: records(2), last: 5, err: errors.New("broker down")},
+			end:     10,
+			want:    []int64{2},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			err := readUntil(context.Background(), tt.reader, tt.end, 10*time.Millisecond, func(record kafka.Message) {
+				got = append(got, record.Offset)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readUntil() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readUntil() read %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeProcessor struct {
+	processed []kafka.Message
+	inspected []kafka.Message
+}
+
+func (f *fakeProcessor) Process(_ context.Context, msg kafka.Message) error {
+	f.processed = append(f.processed, msg)
+	return nil
+}
+
+func (f *fakeProcessor) Inspect(msg kafka.Message) *processor.Inspection {
+	f.inspected = append(f.inspected, msg)
+	return &processor.Inspection{Topic: msg.Topic, Offset: msg.Offset}
+}
+
+func TestReplayRecord(t *testing.T) {
+	record := kafka.Message{
+		Key:   []byte("order-1"),
+		Value: []byte(`{"topic":"orders","offset":7,"original_value":{"id":1},"error_class":"http","response_code":502}`),
+	}
+
+	tests := []struct {
+		name          string
+		record        kafka.Message
+		dryRun        bool
+		wantProcessed int
+		wantInspected int
+		wantReport    replayReport
+	}{
+		{
+			name:          "replayed",
+			record:        record,
+			wantProcessed: 1,
+			wantReport:    replayReport{Scanned: 1, Matched: 1, Delivered: 1},
+		},
+		{
+			name:          "dry run inspects the request",
+			record:        record,
+			dryRun:        true,
+			wantInspected: 1,
+			wantReport:    replayReport{Scanned: 1, Matched: 1},
+		},
+		{
+			name:       "invalid error record",
+			record:     kafka.Message{Value: []byte("not json")},
+			wantReport: replayReport{Scanned: 1, Invalid: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc := &fakeProcessor{}
+			filter, _ := newReplayFilter("", "", "5xx", "")
+			report := &replayReport{}
+
+			replayRecord(context.Background(), zap.NewNop(), proc, filter, tt.record, tt.dryRun, report)
+
+			if len(proc.processed) != tt.wantProcessed || len(proc.inspected) != tt.wantInspected {
+				t.Errorf("processed %d and inspected %d, want %d and %d", len(proc.processed), len(proc.inspected), tt.wantProcessed, tt.wantInspected)
+			}
+			if *report != tt.wantReport {
+				t.Errorf("report = %+v, want %+v", *report, tt.wantReport)
+			}
+			for _, msg := range append(proc.processed, proc.inspected...) {
+				if msg.Topic != "orders" || msg.Offset != 7 || string(msg.Value) != `{"id":1}` || string(msg.Key) != "order-1" {
+					t.Errorf("replayed message = %+v", msg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/processor"
+	"go.uber.org/zap"
+)
+
+// validate checks the config file and the environment the same way the worker does on startup,
+// printing every invalid field and exiting with status 1 when the config is invalid. The sink
+// of every pipeline is also built, so header templates and the secret or descriptor set files
+// they read are checked too.
+//
+// Usage:
+// ```
+// go run ./cmd/console/validate -config sink.yaml
+// CONFIG_FILE=sink.yaml go run ./cmd/console/validate
+// ```
+func main() {
+	path := flag.String("config", os.Getenv(config.FileEnv), "YAML or JSON config file (default CONFIG_FILE)")
+	flag.Parse()
+
+	conf, err := config.Load(*path)
+	if err == nil {
+		err = checkSinks(conf)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid config:")
+		for _, e := range unwrapJoined(err) {
+			fmt.Fprintln(os.Stderr, "  -", e)
+		}
+		os.Exit(1)
+	}
+
+	fmt.Println("config is valid")
+}
+
+// checkSinks builds and closes the sink of every pipeline like the worker does on startup.
+func checkSinks(conf *config.Config) error {
+	var errs []error
+	for i, pipeline := range conf.AllPipelines() {
+		// opening the file of the file sink would create it
+		if pipeline.Config.Sink.Type == processor.SinkTypeFile {
+			continue
+		}
+
+		sink, err := processor.NewSink(pipeline.Name, &pipeline.Config, zap.NewNop())
+		if err != nil {
+			if len(conf.Pipelines) > 0 {
+				err = fmt.Errorf("pipelines[%d] %s: %w", i, pipeline.Name, err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		sink.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// unwrapJoined flattens the errors joined by config.Load so each one is printed on its own line.
+func unwrapJoined(err error) []error {
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, unwrapJoined(e)...)
+	}
+	return errs
+}
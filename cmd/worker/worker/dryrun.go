@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+	"github.com/urbanindo/go-kafka-http-sink/internal/processor"
+	"go.uber.org/zap"
+)
+
+type inspector interface {
+	Inspect(msg kafka.Message) *processor.Inspection
+}
+
+type messageFetcher interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	Close() error
+}
+
+// runDryRun writes what the sink would send for every consumed message as JSON lines,
+// without calling the API and without committing offsets.
+func runDryRun(ctx context.Context, conf *config.Config, dialer *kafka.Dialer, proc inspector) error {
+	var out io.Writer = os.Stdout
+	if conf.DryRun.Output != nil {
+		file, err := os.Create(*conf.DryRun.Output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	var (
+		reader messageFetcher
+		err    error
+	)
+	if conf.DryRun.FromOffset != nil {
+		reader, err = newOffsetRangeReader(conf, dialer)
+	} else {
+		reader, err = newDryRunGroupReader(ctx, conf, dialer)
+	}
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	encoder := json.NewEncoder(out)
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if err := encoder.Encode(proc.Inspect(msg)); err != nil {
+			return err
+		}
+
+		if conf.DryRun.ToOffset != nil && msg.Offset >= *conf.DryRun.ToOffset-1 {
+			logr.Info("dry run reached the end of offset range", zap.Int64("offset", msg.Offset))
+			return nil
+		}
+	}
+}
+
+func newOffsetRangeReader(conf *config.Config, dialer *kafka.Dialer) (messageFetcher, error) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   kafkaclient.Brokers(conf.KafkaConfig),
+		Topic:     conf.KafkaConfig.Topic,
+		Partition: conf.DryRun.Partition,
+		Dialer:    dialer,
+	})
+
+	if err := reader.SetOffset(*conf.DryRun.FromOffset); err != nil {
+		reader.Close()
+		return nil, err
+	}
+
+	return reader, nil
+}
+
+// newDryRunGroupReader consumes with a separate consumer group so the dry run
+// never takes partitions away from the running workers.
+func newDryRunGroupReader(ctx context.Context, conf *config.Config, dialer *kafka.Dialer) (messageFetcher, error) {
+	kafkaConf := conf.KafkaConfig
+	kafkaConf.ConsumerGroupName += "-dry-run"
+
+	if kafkaConf.TopicRegex != nil {
+		return kafkaclient.NewRegexReader(ctx, kafkaConf, dialer, logr)
+	}
+
+	return kafka.NewReader(kafkaclient.NewReaderConfig(kafkaConf, dialer)), nil
+}
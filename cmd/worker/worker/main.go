@@ -2,13 +2,18 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
-	"github.com/segmentio/kafka-go"
 	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/admin"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+	"github.com/urbanindo/go-kafka-http-sink/internal/metrics"
 	"github.com/urbanindo/go-kafka-http-sink/internal/processor"
 	"github.com/urbanindo/go-kafka-http-sink/pkg/helper/logger"
 	"go.uber.org/zap"
@@ -19,9 +24,13 @@ var (
 	code int
 )
 
+// main exits after run returns so that every deferred close in run is executed first.
 func main() {
-	defer os.Exit(code)
+	run()
+	os.Exit(code)
+}
 
+func run() {
 	logr = logger.Named("go_kafka_http_sink")
 	ctx, stop := signal.NotifyContext(
 		context.Background(), syscall.SIGINT, syscall.SIGTERM,
@@ -29,68 +38,78 @@ func main() {
 	defer stop()
 
 	conf := config.Get()
-	kafkaReader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: []string{
-			fmt.Sprintf("%s:%s", conf.KafkaConfig.Broker.Host, conf.KafkaConfig.Broker.Port),
-		},
-		Topic:   conf.KafkaConfig.Topic,
-		GroupID: conf.KafkaConfig.ConsumerGroupName,
-	})
-	defer kafkaReader.Close()
 
-	var (
-		eWriter *kafka.Writer
-		sWriter *kafka.Writer
-	)
+	if conf.DryRun.Enabled {
+		dialer, err := kafkaclient.NewDialer(conf.KafkaConfig)
+		if err != nil {
+			logr.Fatal("failed to initiate kafka dialer", zap.Error(err))
+		}
+		proc := processor.NewProcessor(config.DefaultPipeline, conf, logr, nil, nil)
+		defer proc.Close()
+		logr.Info("kafka http sink started in dry run mode, the API will not be called")
+		if err := runDryRun(ctx, conf, dialer, proc); err != nil {
+			logr.Fatal("dry run failed", zap.Error(err))
+		}
+		return
+	}
 
-	if conf.KafkaConfig.SuccessTopic != nil {
-		sWriter = kafka.NewWriter(kafka.WriterConfig{
-			Brokers: []string{
-				fmt.Sprintf("%s:%s", conf.KafkaConfig.Broker.Host, conf.KafkaConfig.Broker.Port),
-			},
-			Topic:    *conf.KafkaConfig.SuccessTopic,
-			Balancer: kafka.Murmur2Balancer{},
-		})
-		logr.Debug("initiate kafka writer for success message")
+	if conf.MetricsAddr != "" {
+		server := &http.Server{Addr: conf.MetricsAddr, Handler: metricsMux(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logr.Error("metrics server stopped", zap.Error(err))
+			}
+		}()
+		defer server.Close()
 	}
 
-	if conf.KafkaConfig.ErrorTopic != nil {
-		eWriter = kafka.NewWriter(kafka.WriterConfig{
-			Brokers: []string{
-				fmt.Sprintf("%s:%s", conf.KafkaConfig.Broker.Host, conf.KafkaConfig.Broker.Port),
-			},
-			Topic:    *conf.KafkaConfig.ErrorTopic,
-			Balancer: kafka.Murmur2Balancer{},
-		})
-		logr.Debug("initiate kafka writer for error message")
+	var adminServer *admin.Server
+	if conf.Admin.Addr != "" {
+		adminServer = admin.NewServer(conf.Admin.Token, logr)
+		server := &http.Server{Addr: conf.Admin.Addr, Handler: adminServer, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logr.Error("admin server stopped", zap.Error(err))
+			}
+		}()
+		defer server.Close()
 	}
 
-	proc := processor.NewProcessor(conf, logr, eWriter, sWriter)
+	running := newRunningPipelines()
+	if path := os.Getenv(config.FileEnv); path != "" {
+		go watchConfig(ctx, path, conf.ReloadInterval, running)
+	}
 
-	logr.Info("kafka http sink worker started. start for message...")
-	for {
-		msg, err := kafkaReader.ReadMessage(ctx)
-		if err != nil {
-			logr.Error(
-				"failed to read message",
-				zap.Any("message", msg),
-				zap.Error(err),
-			)
-			continue
-		}
-		logr.Debug(
-			"processing message",
-			zap.Error(err),
-			zap.String("payload", string(msg.Value)),
-			zap.Int("offset", int(msg.Offset)),
-		)
+	// Every pipeline runs on its own, a pipeline that fails to start or stops
+	// with an error does not stop the others but the exit code is 1.
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+	for _, pipeline := range conf.AllPipelines() {
+		pipeline := pipeline
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := runPipeline(ctx, pipeline.Name, &pipeline.Config, running, adminServer); err != nil {
+				logr.Error("pipeline stopped with error", zap.String("pipeline", pipeline.Name), zap.Error(err))
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
 
-		if err := proc.Process(ctx, msg); err != nil {
-			logr.Error(
-				"failed to process message",
-				zap.Any("message", msg),
-				zap.Error(err),
-			)
-		}
+	if failed {
+		code = 1
 	}
+	logr.Info("kafka http sink worker stopped", zap.Int("exit_code", code))
+}
+
+func metricsMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default)
+	return mux
 }
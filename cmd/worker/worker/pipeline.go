@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/admin"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+	"github.com/urbanindo/go-kafka-http-sink/internal/pipeline"
+	"github.com/urbanindo/go-kafka-http-sink/internal/processor"
+	"go.uber.org/zap"
+)
+
+// runPipeline consumes the pipeline topics until ctx is cancelled, then drains
+// the in-flight message and flushes the writers.
+func runPipeline(ctx context.Context, name string, conf *config.Config, running *runningPipelines, adminServer *admin.Server) (err error) {
+	logr := logr.With(zap.String("pipeline", name))
+	// NewProcessor panics on invalid settings, recover so the other pipelines keep running.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to start pipeline: %v", r)
+		}
+	}()
+
+	dialer, err := kafkaclient.NewDialer(conf.KafkaConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initiate kafka dialer: %w", err)
+	}
+	transport, err := kafkaclient.NewTransport(conf.KafkaConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initiate kafka transport: %w", err)
+	}
+
+	var (
+		eWriter kafkaclient.Producer
+		sWriter kafkaclient.Producer
+	)
+
+	if conf.KafkaConfig.SuccessTopic != nil {
+		sWriter = kafkaclient.NewWriter(conf.KafkaConfig, *conf.KafkaConfig.SuccessTopic, transport)
+		logr.Debug("initiate kafka writer for success message")
+	}
+
+	if conf.KafkaConfig.ErrorTopic != nil {
+		eWriter = kafkaclient.NewWriter(conf.KafkaConfig, *conf.KafkaConfig.ErrorTopic, transport)
+		logr.Debug("initiate kafka writer for error message")
+	}
+
+	// The processor is built before the reader joins the consumer group, so a panic on
+	// invalid settings does not leave a member holding partitions until the session timeout.
+	proc := processor.NewProcessor(name, conf, logr, eWriter, sWriter)
+
+	kafkaReader, err := pipeline.NewController(func() (kafkaclient.Consumer, error) {
+		if conf.KafkaConfig.TopicRegex != nil {
+			regexReader, err := kafkaclient.NewRegexReader(ctx, conf.KafkaConfig, dialer, logr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to subscribe topics by regex: %w", err)
+			}
+			logr.Info("subscribed topics matching regex", zap.Strings("topics", regexReader.Topics()))
+			return regexReader, nil
+		}
+		return kafka.NewReader(kafkaclient.NewReaderConfig(conf.KafkaConfig, dialer)), nil
+	})
+	if err != nil {
+		closeWriters(sWriter, eWriter)
+		proc.Close()
+		return err
+	}
+
+	running.add(name, conf, proc)
+	if adminServer != nil {
+		adminServer.Register(&admin.Pipeline{
+			Name:       name,
+			Conf:       conf,
+			Dialer:     dialer,
+			Transport:  transport,
+			Controller: kafkaReader,
+			Endpoints:  proc.Endpoints,
+		})
+	}
+
+	// The in-flight message keeps running after the shutdown signal,
+	// it is only cancelled when the drain takes longer than the shutdown timeout.
+	procCtx, cancelProc := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelProc()
+	go func() {
+		<-ctx.Done()
+		select {
+		case <-time.After(conf.ShutdownTimeout):
+			logr.Warn("shutdown timeout reached, cancelling in-flight message")
+			cancelProc()
+		case <-procCtx.Done():
+		}
+	}()
+
+	logr.Info("kafka http sink pipeline started. start for message...")
+	var errs []error
+	if err := pipeline.Run(ctx, procCtx, name, kafkaReader, proc, logr); err != nil {
+		errs = append(errs, err)
+	}
+
+	logr.Info("shutting down kafka http sink pipeline")
+	if err := kafkaReader.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close kafka reader: %w", err))
+	}
+	errs = append(errs, closeWriters(sWriter, eWriter)...)
+	if err := proc.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close sink: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// closeWriters flushes the success and error writers that are configured.
+func closeWriters(sWriter, eWriter kafkaclient.Producer) []error {
+	var errs []error
+	for writerName, writer := range map[string]kafkaclient.Producer{"success": sWriter, "error": eWriter} {
+		if writer == nil {
+			continue
+		}
+		if err := writer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush kafka %s writer: %w", writerName, err))
+		}
+	}
+	return errs
+}
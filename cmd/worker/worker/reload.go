@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/processor"
+	"go.uber.org/zap"
+)
+
+type sinkSetter interface {
+	SetSink(sink processor.Sink) error
+}
+
+// runningPipelines tracks the config applied to every running pipeline, so a
+// reloaded config can be compared against it.
+type runningPipelines struct {
+	mu        sync.Mutex
+	pipelines map[string]*runningPipeline
+}
+
+type runningPipeline struct {
+	conf *config.Config
+	proc sinkSetter
+}
+
+func newRunningPipelines() *runningPipelines {
+	return &runningPipelines{pipelines: map[string]*runningPipeline{}}
+}
+
+func (r *runningPipelines) add(name string, conf *config.Config, proc sinkSetter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pipelines[name] = &runningPipeline{conf: conf, proc: proc}
+}
+
+// watchConfig reloads the config file when it changes or on SIGHUP until ctx is done.
+func watchConfig(ctx context.Context, path string, interval time.Duration, running *runningPipelines) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	modTime := fileModTime(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logr.Info("reloading config on SIGHUP", zap.String("path", path))
+		case <-tick:
+			current := fileModTime(path)
+			if current.Equal(modTime) {
+				continue
+			}
+			logr.Info("reloading changed config", zap.String("path", path))
+		}
+		modTime = fileModTime(path)
+
+		conf, err := config.Load(path)
+		if err != nil {
+			logr.Error("rejected invalid config, keeping the current one", zap.Error(err))
+			continue
+		}
+		running.reload(conf)
+	}
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// reload applies the sink settings of every changed pipeline. The new sinks are all
+// created first, so when any of them fails the current sinks are kept for every pipeline.
+func (r *runningPipelines) reload(conf *config.Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	type change struct {
+		name    string
+		running *runningPipeline
+		conf    config.Config
+		sink    processor.Sink
+	}
+	var changes []change
+
+	names := map[string]bool{}
+	for _, pipeline := range conf.AllPipelines() {
+		names[pipeline.Name] = true
+		plogr := logr.With(zap.String("pipeline", pipeline.Name))
+
+		running, ok := r.pipelines[pipeline.Name]
+		if !ok {
+			plogr.Warn("new pipeline in config, a restart is required to start it")
+			continue
+		}
+		if !reflect.DeepEqual(running.conf.KafkaConfig, pipeline.Config.KafkaConfig) {
+			plogr.Warn("kafka settings changed, a restart is required to apply them")
+		}
+
+		// Only the sink settings are applied, the rest keeps the running values.
+		applied := *running.conf
+		applied.HttpApiUrl = pipeline.Config.HttpApiUrl
+		applied.HttpMethod = pipeline.Config.HttpMethod
+		applied.HttpHeaders = pipeline.Config.HttpHeaders
+		applied.HttpPathParam = pipeline.Config.HttpPathParam
+		applied.HttpSuccess = pipeline.Config.HttpSuccess
+		applied.HttpKeyHeader = pipeline.Config.HttpKeyHeader
+		applied.HttpKafkaHeaders = pipeline.Config.HttpKafkaHeaders
+		applied.HttpBody = pipeline.Config.HttpBody
+		applied.HttpQuery = pipeline.Config.HttpQuery
+		applied.HttpMaxURLLength = pipeline.Config.HttpMaxURLLength
+		applied.HttpClient = pipeline.Config.HttpClient
+		applied.HttpEndpoints = pipeline.Config.HttpEndpoints
+		applied.Sink = pipeline.Config.Sink
+		applied.Idempotency = pipeline.Config.Idempotency
+
+		// every other setting, e.g. dedup or the success format, is used by the processor
+		wanted := pipeline.Config
+		wanted.KafkaConfig = applied.KafkaConfig
+		wanted.Pipelines = applied.Pipelines
+		if !reflect.DeepEqual(wanted, applied) {
+			plogr.Warn("settings other than the sink changed, a restart is required to apply them")
+		}
+
+		if reflect.DeepEqual(*running.conf, applied) {
+			continue
+		}
+
+		sink, err := processor.NewSink(pipeline.Name, &applied, plogr)
+		if err != nil {
+			plogr.Error("rejected invalid sink config, keeping the current config of every pipeline", zap.Error(err))
+			for _, c := range changes {
+				c.sink.Close()
+			}
+			return
+		}
+		changes = append(changes, change{name: pipeline.Name, running: running, conf: applied, sink: sink})
+	}
+
+	for name := range r.pipelines {
+		if !names[name] {
+			logr.Warn("pipeline removed from config, a restart is required to stop it", zap.String("pipeline", name))
+		}
+	}
+
+	for _, c := range changes {
+		if err := c.running.proc.SetSink(c.sink); err != nil {
+			logr.Warn("failed to close the previous sink", zap.String("pipeline", c.name), zap.Error(err))
+		}
+		conf := c.conf
+		c.running.conf = &conf
+		logr.Info("applied reloaded sink config", zap.String("pipeline", c.name))
+	}
+}
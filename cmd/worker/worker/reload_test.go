@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/processor"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// sinkRecorder records the sinks set on reload.
+type sinkRecorder struct {
+	mu    sync.Mutex
+	sinks []processor.Sink
+}
+
+func (s *sinkRecorder) SetSink(sink processor.Sink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sinks = append(s.sinks, sink)
+	return nil
+}
+
+func (s *sinkRecorder) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.sinks)
+}
+
+func (s *sinkRecorder) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sink := range s.sinks {
+		sink.Close()
+	}
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func baseConfig() *config.Config {
+	return &config.Config{
+		KafkaConfig: config.KafkaConfig{
+			Broker: config.KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+			Topic:  "orders",
+		},
+		HttpApiUrl:      "http://api:8080/v1/orders",
+		ReloadInterval:  10 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+// appliedFields changes every setting applied at runtime to a valid sink config.
+var appliedFields = map[string]func(c *config.Config){
+	"HttpApiUrl":       func(c *config.Config) { c.HttpApiUrl = "http://api:8080/v2/orders" },
+	"HttpMethod":       func(c *config.Config) { c.HttpMethod = stringPtr("PUT") },
+	"HttpHeaders":      func(c *config.Config) { c.HttpHeaders = config.HeaderSpecs{"X-Source: {{ .Topic }}"} },
+	"HttpPathParam":    func(c *config.Config) { c.HttpPathParam = stringPtr(":id") },
+	"HttpSuccess":      func(c *config.Config) { c.HttpSuccess.StatusCodes = []string{"200-299"} },
+	"HttpKeyHeader":    func(c *config.Config) { c.HttpKeyHeader = stringPtr("X-Kafka-Key") },
+	"HttpKafkaHeaders": func(c *config.Config) { c.HttpKafkaHeaders.Prefix = "X-Kafka-" },
+	"HttpBody":         func(c *config.Config) { c.HttpBody.Compression = "gzip" },
+	"HttpQuery":        func(c *config.Config) { c.HttpQuery = []string{"id: id"} },
+	"HttpMaxURLLength": func(c *config.Config) { c.HttpMaxURLLength = 4096 },
+	"HttpClient":       func(c *config.Config) { c.HttpClient.Timeout = 5 * time.Second },
+	"HttpEndpoints":    func(c *config.Config) { c.HttpEndpoints.URLs = []string{"http://api-1:8080"} },
+	"Sink":             func(c *config.Config) { c.Sink.Type = processor.SinkTypeStdout },
+	"Idempotency": func(c *config.Config) {
+		c.Idempotency = config.IdempotencyConfig{Header: "Idempotency-Key", Source: "coordinates"}
+	},
+}
+
+// restartFields changes every setting that needs a restart.
+var restartFields = map[string]func(c *config.Config){
+	"KafkaConfig":     func(c *config.Config) { c.KafkaConfig.Topic = "payments" },
+	"DryRun":          func(c *config.Config) { c.DryRun.Enabled = true },
+	"Dedup":           func(c *config.Config) { c.Dedup.Source = "key" },
+	"Success":         func(c *config.Config) { c.Success.Record = true },
+	"MetricsAddr":     func(c *config.Config) { c.MetricsAddr = ":9090" },
+	"Admin":           func(c *config.Config) { c.Admin.Addr = ":8081" },
+	"ReloadInterval":  func(c *config.Config) { c.ReloadInterval = time.Minute },
+	"ShutdownTimeout": func(c *config.Config) { c.ShutdownTimeout = time.Minute },
+}
+
+// TestReloadFieldsCovered fails when a config field is added without deciding
+// whether reload applies it, see runningPipelines.reload.
+func TestReloadFieldsCovered(t *testing.T) {
+	fields := reflect.TypeOf(config.Config{})
+	for i := 0; i < fields.NumField(); i++ {
+		name := fields.Field(i).Name
+		_, applied := appliedFields[name]
+		_, restart := restartFields[name]
+		if !applied && !restart && name != "Pipelines" {
+			t.Errorf("config field %s is neither in appliedFields nor in restartFields", name)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	type change struct {
+		field   string
+		mutate  func(c *config.Config)
+		applied bool
+	}
+	var changes []change
+	for field, mutate := range appliedFields {
+		changes = append(changes, change{field: field, mutate: mutate, applied: true})
+	}
+	for field, mutate := range restartFields {
+		changes = append(changes, change{field: field, mutate: mutate})
+	}
+
+	for _, c := range changes {
+		t.Run(c.field, func(t *testing.T) {
+			core, logs := observer.New(zapcore.WarnLevel)
+			logr = zap.New(core)
+
+			recorder := &sinkRecorder{}
+			defer recorder.close()
+			running := newRunningPipelines()
+			running.add(config.DefaultPipeline, baseConfig(), recorder)
+
+			reloaded := baseConfig()
+			c.mutate(reloaded)
+			running.reload(reloaded)
+
+			got := reflect.ValueOf(*running.pipelines[config.DefaultPipeline].conf).FieldByName(c.field).Interface()
+			want := reflect.ValueOf(*reloaded).FieldByName(c.field).Interface()
+			if c.applied {
+				if recorder.count() != 1 || !reflect.DeepEqual(got, want) {
+					t.Errorf("reload() set %d sinks with %s = %v, want the reloaded %v applied", recorder.count(), c.field, got, want)
+				}
+				if logs.Len() != 0 {
+					t.Errorf("reload() logged %v, want no warning", logs.All())
+				}
+				return
+			}
+
+			if recorder.count() != 0 || reflect.DeepEqual(got, want) {
+				t.Errorf("reload() set %d sinks with %s = %v, want the running value kept", recorder.count(), c.field, got)
+			}
+			if logs.FilterMessageSnippet("restart is required").Len() == 0 {
+				t.Errorf("reload() logged %v, want a restart warning", logs.All())
+			}
+		})
+	}
+}
+
+func TestReloadRejectsInvalidSink(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	logr = zap.New(core)
+
+	orders, payments := &sinkRecorder{}, &sinkRecorder{}
+	running := newRunningPipelines()
+	running.add("orders", baseConfig(), orders)
+	running.add("payments", baseConfig(), payments)
+
+	valid, invalid := baseConfig(), baseConfig()
+	valid.HttpApiUrl = "http://api:8080/v2/orders"
+	invalid.HttpMethod = stringPtr("TRACE")
+	running.reload(&config.Config{Pipelines: []config.PipelineConfig{
+		{Name: "orders", Config: *valid},
+		{Name: "payments", Config: *invalid},
+	}})
+
+	if orders.count() != 0 || payments.count() != 0 {
+		t.Errorf("reload() set %d and %d sinks, want none when any sink is invalid", orders.count(), payments.count())
+	}
+	if running.pipelines["orders"].conf.HttpApiUrl != "http://api:8080/v1/orders" {
+		t.Errorf("reload() applied %s, want the running config kept", running.pipelines["orders"].conf.HttpApiUrl)
+	}
+	if logs.FilterMessageSnippet("rejected invalid sink config").Len() != 1 {
+		t.Errorf("reload() logged %v, want the rejected sink", logs.All())
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	logr = zap.NewNop()
+	t.Setenv("KAFKA_BROKER_HOST", "kafka")
+	t.Setenv("KAFKA_BROKER_PORT", "9092")
+	t.Setenv("KAFKA_TOPIC", "orders")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(url string) {
+		if err := os.WriteFile(path, []byte("http_api_url: "+url+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("http://api:8080/v1/orders")
+	conf, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := &sinkRecorder{}
+	defer recorder.close()
+	running := newRunningPipelines()
+	running.add(config.DefaultPipeline, conf, recorder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watchConfig(ctx, path, 10*time.Millisecond, running)
+		close(done)
+	}()
+
+	// the watcher may not have read the first modification time yet, so keep
+	// moving it until the change is applied
+	write("http://api:8080/v2/orders")
+	for i := 1; recorder.count() == 0 && i <= 500; i++ {
+		modTime := time.Now().Add(time.Duration(i) * time.Second)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if recorder.count() != 1 {
+		t.Fatalf("watchConfig() set %d sinks, want 1", recorder.count())
+	}
+	if got := running.pipelines[config.DefaultPipeline].conf.HttpApiUrl; got != "http://api:8080/v2/orders" {
+		t.Errorf("watchConfig() applied %s, want the changed URL", got)
+	}
+}
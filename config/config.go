@@ -2,9 +2,12 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/yaml.v3"
 )
 
 type Stage string
@@ -17,35 +20,388 @@ const (
 )
 
 type KafkaBrokerConfig struct {
-	Host string `envconfig:"HOST"`
-	Port string `envconfig:"PORT"`
+	Host string `envconfig:"HOST" yaml:"host"`
+	Port string `envconfig:"PORT" yaml:"port"`
+	// Hosts is a comma separated list of host:port, takes precedence over Host and Port.
+	// Example: KAFKA_BROKER_HOSTS=kafka-1:9092,kafka-2:9092,kafka-3:9092
+	Hosts []string `envconfig:"HOSTS" yaml:"hosts"`
+}
+
+// DefaultConsumerMaxBytes is the kafka-go default of MaxBytes, it is set explicitly since
+// kafka-go validates MinBytes against MaxBytes before applying its defaults.
+const DefaultConsumerMaxBytes = 1e6
+
+// KafkaConsumerConfig tunes the consumer group reader.
+// Zero values fall back to the kafka-go defaults.
+type KafkaConsumerConfig struct {
+	// StartOffset is used when the group has no committed offset: earliest or latest. Default: earliest
+	StartOffset       string        `envconfig:"START_OFFSET" yaml:"start_offset"`
+	MinBytes          int           `envconfig:"MIN_BYTES" yaml:"min_bytes"`
+	MaxBytes          int           `envconfig:"MAX_BYTES" yaml:"max_bytes"`
+	MaxWait           time.Duration `envconfig:"MAX_WAIT" yaml:"max_wait"`
+	SessionTimeout    time.Duration `envconfig:"SESSION_TIMEOUT" yaml:"session_timeout"`
+	HeartbeatInterval time.Duration `envconfig:"HEARTBEAT_INTERVAL" yaml:"heartbeat_interval"`
+	// CommitInterval commits offsets periodically instead of after every message when set.
+	CommitInterval time.Duration `envconfig:"COMMIT_INTERVAL" yaml:"commit_interval"`
+	// RebalanceStrategy is either range or roundrobin. Default: range
+	RebalanceStrategy string `envconfig:"REBALANCE_STRATEGY" yaml:"rebalance_strategy"`
+	// IsolationLevel is either read_uncommitted or read_committed. Default: read_uncommitted
+	IsolationLevel string `envconfig:"ISOLATION_LEVEL" yaml:"isolation_level"`
+}
+
+// KafkaSASLConfig enables SASL authentication when Mechanism is set.
+// Supported mechanisms: PLAIN, SCRAM-SHA-256, SCRAM-SHA-512
+type KafkaSASLConfig struct {
+	Mechanism *string `envconfig:"MECHANISM" yaml:"mechanism"`
+	Username  string  `envconfig:"USERNAME" yaml:"username"`
+	Password  string  `envconfig:"PASSWORD" yaml:"password"`
+}
+
+// KafkaTLSConfig enables TLS to the brokers. CAFile is only needed for private CAs,
+// CertFile and KeyFile are only needed for mutual TLS.
+type KafkaTLSConfig struct {
+	Enabled            bool    `envconfig:"ENABLED" yaml:"enabled"`
+	CAFile             *string `envconfig:"CA_FILE" yaml:"ca_file"`
+	CertFile           *string `envconfig:"CERT_FILE" yaml:"cert_file"`
+	KeyFile            *string `envconfig:"KEY_FILE" yaml:"key_file"`
+	ServerName         *string `envconfig:"SERVER_NAME" yaml:"server_name"`
+	InsecureSkipVerify bool    `envconfig:"INSECURE_SKIP_VERIFY" yaml:"insecure_skip_verify"`
 }
 
 type KafkaConfig struct {
-	Broker            KafkaBrokerConfig `envconfig:"BROKER"`
-	Topic             string            `envconfig:"TOPIC"`
-	ErrorTopic        *string           `envconfig:"ERROR_TOPIC"`
-	SuccessTopic      *string           `envconfig:"SUCCESS_TOPIC"`
-	SchemaRegistryUrl *string           `envconfig:"SCHEMA_REGISTRY_URL"`
-	ConsumerGroupName string            `envconfig:"CONSUMER_GROUP_NAME"`
+	Broker KafkaBrokerConfig `envconfig:"BROKER" yaml:"broker"`
+	SASL   KafkaSASLConfig   `envconfig:"SASL" yaml:"sasl"`
+	TLS    KafkaTLSConfig    `envconfig:"TLS" yaml:"tls"`
+	Topic  string            `envconfig:"TOPIC" yaml:"topic"`
+	// Topics is a comma separated list of topics consumed by the same consumer group.
+	Topics []string `envconfig:"TOPICS" yaml:"topics"`
+	// TopicRegex subscribes to every topic matching the pattern, the topic list
+	// is refreshed from the cluster metadata every TopicRefreshInterval.
+	TopicRegex           *string             `envconfig:"TOPIC_REGEX" yaml:"topic_regex"`
+	TopicRefreshInterval time.Duration       `envconfig:"TOPIC_REFRESH_INTERVAL" yaml:"topic_refresh_interval" default:"1m"`
+	ErrorTopic           *string             `envconfig:"ERROR_TOPIC" yaml:"error_topic"`
+	SuccessTopic         *string             `envconfig:"SUCCESS_TOPIC" yaml:"success_topic"`
+	SchemaRegistryUrl    *string             `envconfig:"SCHEMA_REGISTRY_URL" yaml:"schema_registry_url"`
+	ConsumerGroupName    string              `envconfig:"CONSUMER_GROUP_NAME" yaml:"consumer_group_name"`
+	Consumer             KafkaConsumerConfig `envconfig:"CONSUMER" yaml:"consumer"`
+}
+
+// DryRunConfig builds the requests without calling the API and writes them as JSON lines.
+// By default it consumes with a separate "<group>-dry-run" consumer group without committing.
+// When FromOffset is set, it reads the fixed offset range of KAFKA_TOPIC Partition instead.
+type DryRunConfig struct {
+	Enabled bool `envconfig:"ENABLED" yaml:"enabled"`
+	// Output is the file to write to. Default: stdout
+	Output     *string `envconfig:"OUTPUT" yaml:"output"`
+	Partition  int     `envconfig:"PARTITION" yaml:"partition"`
+	FromOffset *int64  `envconfig:"FROM_OFFSET" yaml:"from_offset"`
+	// ToOffset is exclusive, when not set it reads until stopped.
+	ToOffset *int64 `envconfig:"TO_OFFSET" yaml:"to_offset"`
+}
+
+// GrpcSinkConfig calls a unary gRPC method with the decoded JSON value converted to the request message.
+// The message types are resolved from a FileDescriptorSet, e.g. built with
+// `protoc --include_imports --descriptor_set_out=service.pb service.proto`.
+type GrpcSinkConfig struct {
+	Target string `envconfig:"TARGET" yaml:"target"`
+	// Method is the full method name. Example: /orders.v1.OrderService/CreateOrder
+	Method        string `envconfig:"METHOD" yaml:"method"`
+	DescriptorSet string `envconfig:"DESCRIPTOR_SET" yaml:"descriptor_set"`
+	// Metadata is a comma separated list of "Name: value" specs, same as HTTP_HEADERS.
+	Metadata HeaderSpecs   `envconfig:"METADATA" yaml:"metadata"`
+	Insecure bool          `envconfig:"INSECURE" yaml:"insecure"`
+	Timeout  time.Duration `envconfig:"TIMEOUT" yaml:"timeout" default:"30s"`
+	// CAFile is only needed for private CAs, CertFile and KeyFile are only needed for mutual TLS.
+	CAFile     string `envconfig:"CA_FILE" yaml:"ca_file"`
+	CertFile   string `envconfig:"CERT_FILE" yaml:"cert_file"`
+	KeyFile    string `envconfig:"KEY_FILE" yaml:"key_file"`
+	ServerName string `envconfig:"SERVER_NAME" yaml:"server_name"`
+}
+
+// SinkConfig selects where the decoded messages are delivered.
+type SinkConfig struct {
+	// Type is one of http, grpc, file or stdout. Default: http
+	Type string `envconfig:"TYPE" yaml:"type" default:"http"`
+	// Envelope wraps the value with the message topic, partition, offset, key and headers
+	// for the file and stdout sinks, otherwise the value is written as is.
+	Envelope bool `envconfig:"ENVELOPE" yaml:"envelope"`
+	// FilePath is the NDJSON file the file sink appends to.
+	FilePath string         `envconfig:"FILE_PATH" yaml:"file_path"`
+	Grpc     GrpcSinkConfig `envconfig:"GRPC" yaml:"grpc"`
+}
+
+// IdempotencyConfig adds a header with a key that is the same on every delivery of a
+// message, including retries and replays from the error topic, so the API can deduplicate.
+type IdempotencyConfig struct {
+	// Header is the header name, or the gRPC metadata key. Disabled when empty.
+	// Example: IDEMPOTENCY_HEADER=Idempotency-Key
+	Header string `envconfig:"HEADER" yaml:"header"`
+	// Source is one of:
+	//   - coordinates: "<topic>-<partition>-<offset>"
+	//   - hash: SHA-256 of the message key and value
+	//   - field: the value of Field in the decoded JSON value
+	// Default: coordinates
+	Source string `envconfig:"SOURCE" yaml:"source" default:"coordinates"`
+	// Field is the dot separated path of the payload field. Example: order.id
+	Field string `envconfig:"FIELD" yaml:"field"`
+}
+
+// HttpSuccessConfig decides which HTTP responses are successful, e.g. to catch
+// legacy endpoints answering 200 with {"success": false}. The other responses fail
+// and are written to the error topic.
+type HttpSuccessConfig struct {
+	// StatusCodes is a comma separated list of status codes or ranges. Default: 200-299
+	// Example: HTTP_SUCCESS_STATUS_CODES=200,202-204
+	StatusCodes []string `envconfig:"STATUS_CODES" yaml:"status_codes"`
+	// Body is a comma separated list of predicates the JSON response must match, either
+	// "<field path> == <value>", "<field path> != <value>" or "<field path>" for a field
+	// that must be present. The value is JSON, or a string when not valid JSON.
+	// Example: HTTP_SUCCESS_BODY='success == true,data.id'
+	Body []string `envconfig:"BODY" yaml:"body"`
+	// Headers is a comma separated list of response headers that must be present.
+	Headers []string `envconfig:"HEADERS" yaml:"headers"`
+}
+
+// HttpKafkaHeadersConfig selects, renames and encodes the Kafka message headers sent as HTTP headers.
+// HTTP_HEADERS and the body encoding headers are not overridden by a Kafka header of the same name.
+type HttpKafkaHeadersConfig struct {
+	// Allow is a comma separated list of the Kafka headers to send, every header when empty.
+	// A trailing * matches by prefix. Example: HTTP_KAFKA_HEADERS_ALLOW=trace-id,x-*
+	Allow []string `envconfig:"ALLOW" yaml:"allow"`
+	// Deny is a comma separated list of the Kafka headers never sent, it takes precedence over Allow.
+	Deny *[]string `envconfig:"DENY" yaml:"deny"` // Default: id
+	// Rename is a comma separated list of "kafka-name: HTTP-Name" specs.
+	Rename []string `envconfig:"RENAME" yaml:"rename"`
+	// Prefix is added to the names of the headers that are not renamed. Example: X-Kafka-
+	Prefix string `envconfig:"PREFIX" yaml:"prefix"`
+	// Templates is a comma separated list of "kafka-name: template" specs rendering the value
+	// with the message context and the header value as .Value. Example: 'tenant: t-{{ .Value }}'
+	Templates []string `envconfig:"TEMPLATES" yaml:"templates"`
+	// Binary is what to do with values that are not printable UTF-8: base64 or drop. Default: base64
+	Binary string `envconfig:"BINARY" yaml:"binary" default:"base64"`
+}
+
+// HttpBodyConfig encodes the request body.
+type HttpBodyConfig struct {
+	// Encoding is one of:
+	//   - json: the decoded JSON value
+	//   - form: the decoded JSON object flattened to x-www-form-urlencoded, e.g. a[b]=1&c[0]=2
+	//   - ndjson: a JSON array value as one line per element, other values as one line
+	//   - raw: the original message value bytes, without decoding
+	//   - avro: the Avro binary of a schema registry message, without the wire format header
+	// Default: unset, the decoded value is sent without Content-Type. It is encoded as json
+	// when only the content type or the compression is set.
+	Encoding string `envconfig:"ENCODING" yaml:"encoding"`
+	// ContentType overrides the Content-Type of the encoding.
+	ContentType string `envconfig:"CONTENT_TYPE" yaml:"content_type"`
+	// Compression compresses the body with gzip or zstd and sets the Content-Encoding header.
+	Compression string `envconfig:"COMPRESSION" yaml:"compression"`
+}
+
+// HttpClientConfig tunes the timeouts and the connection pool of the HTTP client.
+// A zero duration or count keeps the Go default, which for timeouts is no timeout.
+type HttpClientConfig struct {
+	// Timeout is the total time of a request, including reading the response body.
+	Timeout               time.Duration `envconfig:"TIMEOUT" yaml:"timeout" default:"30s"`
+	ConnectTimeout        time.Duration `envconfig:"CONNECT_TIMEOUT" yaml:"connect_timeout" default:"10s"`
+	TLSHandshakeTimeout   time.Duration `envconfig:"TLS_HANDSHAKE_TIMEOUT" yaml:"tls_handshake_timeout" default:"10s"`
+	ResponseHeaderTimeout time.Duration `envconfig:"RESPONSE_HEADER_TIMEOUT" yaml:"response_header_timeout"`
+	// KeepAlive is the TCP keep-alive period, DisableKeepAlives closes the connection after every request.
+	KeepAlive           time.Duration `envconfig:"KEEP_ALIVE" yaml:"keep_alive" default:"30s"`
+	DisableKeepAlives   bool          `envconfig:"DISABLE_KEEP_ALIVES" yaml:"disable_keep_alives"`
+	IdleConnTimeout     time.Duration `envconfig:"IDLE_CONN_TIMEOUT" yaml:"idle_conn_timeout" default:"90s"`
+	MaxIdleConns        int           `envconfig:"MAX_IDLE_CONNS" yaml:"max_idle_conns" default:"100"`
+	MaxIdleConnsPerHost int           `envconfig:"MAX_IDLE_CONNS_PER_HOST" yaml:"max_idle_conns_per_host" default:"10"`
+	MaxConnsPerHost     int           `envconfig:"MAX_CONNS_PER_HOST" yaml:"max_conns_per_host"`
+	// HTTP2 negotiates HTTP/2 with TLS endpoints.
+	HTTP2 bool `envconfig:"HTTP2" yaml:"http2" default:"true"`
+	// Proxy is the proxy URL, HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used when empty.
+	// Example: HTTP_CLIENT_PROXY=http://proxy.internal:3128
+	Proxy string `envconfig:"PROXY" yaml:"proxy"`
+}
+
+// HttpEndpointsConfig balances the requests across several replicas of the API without
+// a load balancer in front. The scheme and host of HttpApiUrl are replaced by the selected
+// endpoint, so HttpApiUrl can also be only the path, e.g. /v1/orders/:param.
+type HttpEndpointsConfig struct {
+	// URLs are the base URLs of the replicas. Disabled when empty.
+	// Example: HTTP_ENDPOINTS_URLS=http://api-1:8080,http://api-2:8080
+	URLs []string `envconfig:"URLS" yaml:"urls"`
+	// Strategy is one of round_robin or key_hash.
+	//   - round_robin: the endpoints in turn
+	//   - key_hash: the same endpoint for the same message key while it is healthy
+	Strategy string `envconfig:"STRATEGY" yaml:"strategy" default:"round_robin"`
+	// EjectAfter consecutive transport errors or 5xx responses eject the endpoint for EjectFor.
+	// 0 disables the ejection. When every endpoint is ejected they are all used again.
+	EjectAfter int           `envconfig:"EJECT_AFTER" yaml:"eject_after" default:"5"`
+	EjectFor   time.Duration `envconfig:"EJECT_FOR" yaml:"eject_for" default:"30s"`
+}
+
+// DedupConfig drops a message whose dedup key was already delivered within the window.
+// Only delivered messages are remembered, so a failed message can be retried or replayed.
+type DedupConfig struct {
+	// Source is one of key, header or field. Disabled when empty.
+	//   - key: the message key
+	//   - header: the value of the Header message header
+	//   - field: the value of Field in the decoded JSON value
+	Source string `envconfig:"SOURCE" yaml:"source"`
+	Header string `envconfig:"HEADER" yaml:"header"`
+	// Field is the dot separated path of the payload field. Example: event.id
+	Field string `envconfig:"FIELD" yaml:"field"`
+	// Window is how long a delivered key is remembered.
+	Window time.Duration `envconfig:"WINDOW" yaml:"window" default:"10m"`
+	// Size is the maximum number of keys kept in memory, the least recently delivered are dropped first.
+	Size int `envconfig:"SIZE" yaml:"size" default:"100000"`
+}
+
+// SuccessConfig shapes the messages written to KAFKA_SUCCESS_TOPIC, by default the value
+// is the raw response body with the original message key.
+type SuccessConfig struct {
+	// Record writes a JSON record with the response and the source message coordinates instead.
+	Record bool `envconfig:"RECORD" yaml:"record"`
+	// ResponseHeaders is a comma separated list of response headers kept in the record.
+	ResponseHeaders []string `envconfig:"RESPONSE_HEADERS" yaml:"response_headers"`
+	// IncludeRequestBody keeps the request body in the record.
+	IncludeRequestBody bool `envconfig:"INCLUDE_REQUEST_BODY" yaml:"include_request_body"`
+	// PropagateHeaders copies the headers of the consumed message to the success message.
+	PropagateHeaders bool `envconfig:"PROPAGATE_HEADERS" yaml:"propagate_headers"`
+	// HeaderMappings is a comma separated list of "name: source" specs added as headers of the
+	// success message. Source is body.<field path> for a field of the JSON response or
+	// header.<name> for a response header, a source that is not found is skipped.
+	// Example: SUCCESS_HEADER_MAPPINGS='order-id: body.data.id,location: header.Location'
+	HeaderMappings []string `envconfig:"HEADER_MAPPINGS" yaml:"header_mappings"`
+	// ValueMappings reshapes the value into a JSON object with a field per "name: source" spec,
+	// the JSON type of body fields is kept.
+	ValueMappings []string `envconfig:"VALUE_MAPPINGS" yaml:"value_mappings"`
+	// Key is the source of the success message key, the original message key is kept when not found.
+	// Example: SUCCESS_KEY=body.data.id
+	Key string `envconfig:"KEY" yaml:"key"`
+}
+
+// AdminConfig serves the admin API on Addr when set, every request needs the Token.
+type AdminConfig struct {
+	Addr  string `envconfig:"ADDR" yaml:"addr"`
+	Token string `envconfig:"TOKEN" yaml:"token"`
+}
+
+// HeaderSpecs are "Name: value" header specs. In an env var they are separated by commas,
+// so a comma in a value must be escaped as \, while a config file lists them as is.
+type HeaderSpecs []string
+
+// Decode splits the env var on the commas that are not escaped.
+func (h *HeaderSpecs) Decode(value string) error {
+	var specs HeaderSpecs
+	var spec strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value) && value[i+1] == ',':
+			spec.WriteByte(',')
+			i++
+		case value[i] == ',':
+			specs = append(specs, spec.String())
+			spec.Reset()
+		default:
+			spec.WriteByte(value[i])
+		}
+	}
+	*h = append(specs, spec.String())
+	return nil
 }
 
 type Config struct {
-	KafkaConfig KafkaConfig `envconfig:"KAFKA"`
-	HttpApiUrl  string      `envconfig:"HTTP_API_URL"`
-	HttpMethod  *string     `envconfig:"HTTP_METHOD"` // Default: POST
-	HttpHeaders *[]string   `envconfig:"HTTP_HEADERS"`
+	KafkaConfig KafkaConfig `envconfig:"KAFKA" yaml:"kafka"`
+	// HttpApiUrl can be a template using the message context, the key, topic and header
+	// values are path escaped. pathEscape and queryEscape escape the other values.
+	// Example: HTTP_API_URL=http://api.com/v1/{{ .Topic }}/events?token={{ env "TOKEN" | queryEscape }}
+	HttpApiUrl string `envconfig:"HTTP_API_URL" yaml:"http_api_url"`
+	// HttpMethod is one of POST, PUT, PATCH, DELETE, GET or HEAD. GET and HEAD send no body.
+	HttpMethod *string `envconfig:"HTTP_METHOD" yaml:"http_method"` // Default: POST
+	// HttpHeaders is a comma separated list of "Name: value" specs, a comma in a value is
+	// escaped as \,. The value is a template that can read secrets and message data, see
+	// processor.parseHeaderSpec.
+	// Example: HTTP_HEADERS='Authorization: Bearer {{ file "/secrets/token" }},Accept: a\, b'
+	HttpHeaders HeaderSpecs `envconfig:"HTTP_HEADERS" yaml:"http_headers"`
 	// PathParam determines which part of the message key to use as path parameter.
 	// If set, the `:param` placeholder in HttpApiUrl will be replaced with the message key.
 	// Example: HttpApiUrl="http://api.com/v1/users/:param" + message.key="user123"
 	// → "http://api.com/v1/users/user123"
-	HttpPathParam *string `envconfig:"HTTP_PATH_PARAM"`
+	HttpPathParam *string           `envconfig:"HTTP_PATH_PARAM" yaml:"http_path_param"`
+	HttpSuccess   HttpSuccessConfig `envconfig:"HTTP_SUCCESS" yaml:"http_success"`
+	// HttpKeyHeader is the HTTP header with the message key, empty disables it.
+	HttpKeyHeader    *string                `envconfig:"HTTP_KEY_HEADER" yaml:"http_key_header"` // Default: kafka_key
+	HttpKafkaHeaders HttpKafkaHeadersConfig `envconfig:"HTTP_KAFKA_HEADERS" yaml:"http_kafka_headers"`
+	HttpBody         HttpBodyConfig         `envconfig:"HTTP_BODY" yaml:"http_body"`
+	// HttpQuery is a comma separated list of "param: field path" specs adding the fields of the
+	// decoded JSON value as query parameters, e.g. for GET trigger URLs. Arrays are repeated
+	// parameters, a field that is not found is skipped.
+	// Example: HTTP_QUERY='id: order.id,status: order.status'
+	HttpQuery []string `envconfig:"HTTP_QUERY" yaml:"http_query"`
+	// HttpMaxURLLength fails the message when the final URL is longer. Default: 8192
+	HttpMaxURLLength int              `envconfig:"HTTP_MAX_URL_LENGTH" yaml:"http_max_url_length"`
+	HttpClient       HttpClientConfig `envconfig:"HTTP_CLIENT" yaml:"http_client"`
+	// HttpEndpoints sends to several base URLs, a transport error fails over to the next endpoint.
+	HttpEndpoints HttpEndpointsConfig `envconfig:"HTTP_ENDPOINTS" yaml:"http_endpoints"`
+	DryRun        DryRunConfig        `envconfig:"DRY_RUN" yaml:"dry_run"`
+	Sink          SinkConfig          `envconfig:"SINK" yaml:"sink"`
+	Idempotency   IdempotencyConfig   `envconfig:"IDEMPOTENCY" yaml:"idempotency"`
+	Dedup         DedupConfig         `envconfig:"DEDUP" yaml:"dedup"`
+	Success       SuccessConfig       `envconfig:"SUCCESS" yaml:"success"`
+	// MetricsAddr serves the Prometheus metrics on /metrics when set. Example: :9090
+	MetricsAddr string      `envconfig:"METRICS_ADDR" yaml:"metrics_addr"`
+	Admin       AdminConfig `envconfig:"ADMIN" yaml:"admin"`
+	// Pipelines runs several consumers in one process, they can only be set in the config file.
+	Pipelines []PipelineConfig `ignored:"true" yaml:"pipelines"`
+	// ReloadInterval is how often the config file is checked for changes, 0 disables it.
+	// The config file is also reloaded on SIGHUP. Only the sink settings are applied
+	// at runtime, changed Kafka settings need a restart.
+	ReloadInterval time.Duration `envconfig:"CONFIG_RELOAD_INTERVAL" yaml:"config_reload_interval" default:"10s"`
+	// ShutdownTimeout is how long the in-flight message may take to finish after SIGTERM.
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" yaml:"shutdown_timeout" default:"30s"`
 }
 
+// PipelineConfig is one topic to sink flow when running several in one process.
+// Every setting that is not set in the pipeline is inherited from the top level config.
+type PipelineConfig struct {
+	Name   string
+	Config Config
+
+	node yaml.Node
+}
+
+// UnmarshalYAML keeps the pipeline node, it is decoded over the top level config once
+// the whole file and the environment are loaded.
+func (p *PipelineConfig) UnmarshalYAML(node *yaml.Node) error {
+	var fields struct {
+		Name string `yaml:"name"`
+	}
+	if err := node.Decode(&fields); err != nil {
+		return err
+	}
+
+	p.Name = fields.Name
+	p.node = *node
+	return nil
+}
+
+// AllPipelines returns the configured pipelines, or the top level config as the
+// single "default" pipeline when none is configured.
+func (c *Config) AllPipelines() []PipelineConfig {
+	if len(c.Pipelines) > 0 {
+		return c.Pipelines
+	}
+	return []PipelineConfig{{Name: DefaultPipeline, Config: *c}}
+}
+
+// DefaultPipeline is the name of the pipeline configured by the top level config.
+const DefaultPipeline = "default"
+
+// FileEnv is the env var with the path of the YAML or JSON config file.
+const FileEnv = "CONFIG_FILE"
+
 var cfgSync sync.Once
 var confSingleton Config
 
-// Get is Getter for Config
+// Get is Getter for Config, the config file is read from CONFIG_FILE when set, see Load.
 //
 // Usage:
 // ```
@@ -55,11 +411,13 @@ var confSingleton Config
 // ```
 func Get() *Config {
 	cfgSync.Do(func() {
-		err := envconfig.Process("", &confSingleton)
+		conf, err := Load(os.Getenv(FileEnv))
 
 		if err != nil {
-			panic(fmt.Sprintln("Couldn't process config", err))
+			panic(fmt.Sprintln("Invalid config", err))
 		}
+
+		confSingleton = *conf
 	})
 
 	return &confSingleton
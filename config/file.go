@@ -0,0 +1,249 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// interpolation matches ${VAR} and ${VAR:-default} in the config file.
+var interpolation = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Load reads the config from the environment and the YAML or JSON file at path when not empty.
+// The file uses the env var names in lower case, nested by prefix, e.g. KAFKA_BROKER_HOSTS is
+// kafka.broker.hosts. Values set in the environment take precedence over the file, and
+// ${VAR} or ${VAR:-default} in the file values are replaced by the environment.
+// Every invalid field is reported at once.
+func Load(path string) (*Config, error) {
+	var conf Config
+	if err := envconfig.Process("", &conf); err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		if err := conf.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &conf, nil
+}
+
+// LoadKafka reads only the Kafka settings from the environment and the file at path when
+// not empty, for the tools that read or write topics without running a sink. Only the
+// broker and SASL settings are validated, the rest of the config may be unset or invalid.
+func LoadKafka(path string) (*KafkaConfig, error) {
+	var conf KafkaConfig
+	if err := envconfig.Process("KAFKA", &conf); err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		var file struct {
+			Kafka yaml.Node `yaml:"kafka"`
+		}
+		if err := yaml.Unmarshal(content, &file); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if file.Kafka.Kind != 0 {
+			content, err = yaml.Marshal(&file.Kafka)
+			if err != nil {
+				return nil, err
+			}
+			content, err = interpolate(content)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if err := decodeStrict(path+": kafka", content, &conf); err != nil {
+				return nil, err
+			}
+		}
+
+		var env KafkaConfig
+		if err := envconfig.Process("KAFKA", &env); err != nil {
+			return nil, err
+		}
+		overrideFromEnv(reflect.ValueOf(&conf).Elem(), reflect.ValueOf(&env).Elem(), "KAFKA")
+	}
+
+	errs := append(conf.Broker.validate(), conf.SASL.validate()...)
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return &conf, nil
+}
+
+// loadFile decodes the file over the config and applies the environment again on top of it.
+func (c *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	content, err = interpolate(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := decodeStrict(path, content, c); err != nil {
+		return err
+	}
+
+	var env Config
+	if err := envconfig.Process("", &env); err != nil {
+		return err
+	}
+	overrideFromEnv(reflect.ValueOf(c).Elem(), reflect.ValueOf(&env).Elem(), "")
+
+	return c.resolvePipelines(path)
+}
+
+// resolvePipelines decodes every pipeline over a copy of the top level config,
+// so a pipeline only needs the settings that differ from the top level.
+func (c *Config) resolvePipelines(path string) error {
+	var errs []error
+	for i := range c.Pipelines {
+		pipeline := &c.Pipelines[i]
+		content, err := yaml.Marshal(&pipeline.node)
+		if err != nil {
+			return err
+		}
+
+		base, err := c.clone()
+		if err != nil {
+			return err
+		}
+		fields := struct {
+			Name   string `yaml:"name"`
+			Config `yaml:",inline"`
+		}{Config: base}
+		if err := decodeStrict(fmt.Sprintf("%s: pipelines[%d]", path, i), content, &fields); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pipeline.Config = fields.Config
+	}
+
+	return errors.Join(errs...)
+}
+
+// clone deep copies the config without the pipelines.
+func (c *Config) clone() (Config, error) {
+	base := *c
+	base.Pipelines = nil
+
+	content, err := json.Marshal(base)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to copy config: %w", err)
+	}
+
+	var clone Config
+	if err := json.Unmarshal(content, &clone); err != nil {
+		return Config{}, fmt.Errorf("failed to copy config: %w", err)
+	}
+	return clone, nil
+}
+
+// decodeStrict decodes the YAML content rejecting unknown fields, every type error is reported.
+func decodeStrict(path string, content []byte, out interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			errs := make([]error, 0, len(typeErr.Errors))
+			for _, e := range typeErr.Errors {
+				errs = append(errs, fmt.Errorf("%s: %s", path, e))
+			}
+			return errors.Join(errs...)
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// interpolate replaces ${VAR} and ${VAR:-default} with the environment in the values of the file,
+// comments are left as is. Every variable that is not set and has no default is reported.
+func interpolate(content []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind == yaml.ScalarNode && interpolation.MatchString(node.Value) {
+			if node.Style == 0 {
+				// let the interpolated plain value resolve to its own type, e.g. a number
+				node.Tag = ""
+			}
+			node.Value = interpolation.ReplaceAllStringFunc(node.Value, func(match string) string {
+				groups := interpolation.FindStringSubmatch(match)
+				if value, ok := os.LookupEnv(groups[1]); ok {
+					return value
+				}
+				if groups[2] != "" {
+					return groups[3]
+				}
+				errs = append(errs, fmt.Errorf("environment variable %s is not set", groups[1]))
+				return match
+			})
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	walk(&root)
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if root.Kind == 0 {
+		return nil, nil
+	}
+	return yaml.Marshal(&root)
+}
+
+// overrideFromEnv copies every field whose env var is set from env to conf,
+// the env var names are built the same way as envconfig does with the envconfig tags.
+func overrideFromEnv(conf, env reflect.Value, prefix string) {
+	for i := 0; i < conf.NumField(); i++ {
+		field := conf.Type().Field(i)
+		key := field.Tag.Get("envconfig")
+		if key == "" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			overrideFromEnv(conf.Field(i), env.Field(i), key)
+			continue
+		}
+
+		if _, ok := os.LookupEnv(key); ok {
+			conf.Field(i).Set(env.Field(i))
+		}
+	}
+}
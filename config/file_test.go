@@ -0,0 +1,265 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		env        map[string]string
+		check      func(t *testing.T, conf *Config)
+		wantErrors []string
+	}{
+		{
+			name: "yaml with interpolation",
+			file: `
+kafka:
+  broker:
+    hosts: [kafka-1:9092, kafka-2:9092]
+  topic: orders
+  consumer_group_name: ${GROUP:-sink}
+  consumer:
+    min_bytes: ${MIN_BYTES}
+http_api_url: http://${API_HOST}/v1/orders
+http_headers:
+  - "Authorization: Bearer {{ env \"TOKEN\" }}"
+shutdown_timeout: 10s
+`,
+			env: map[string]string{"API_HOST": "api:8080", "MIN_BYTES": "1024"},
+			check: func(t *testing.T, conf *Config) {
+				if len(conf.KafkaConfig.Broker.Hosts) != 2 || conf.KafkaConfig.ConsumerGroupName != "sink" || conf.KafkaConfig.Consumer.MinBytes != 1024 {
+					t.Errorf("kafka = %+v", conf.KafkaConfig)
+				}
+				if conf.HttpApiUrl != "http://api:8080/v1/orders" || len(conf.HttpHeaders) != 1 {
+					t.Errorf("http = %s %v", conf.HttpApiUrl, conf.HttpHeaders)
+				}
+				if conf.ShutdownTimeout != 10*time.Second || conf.KafkaConfig.TopicRefreshInterval != time.Minute {
+					t.Errorf("durations = %s %s, want file value and default", conf.ShutdownTimeout, conf.KafkaConfig.TopicRefreshInterval)
+				}
+			},
+		},
+		{
+			name: "env overrides json",
+			file: `{"kafka": {"broker": {"host": "kafka", "port": "9092"}, "topic": "orders"}, "http_api_url": "http://api:8080", "shutdown_timeout": "10s"}`,
+			env:  map[string]string{"KAFKA_TOPIC": "payments", "SHUTDOWN_TIMEOUT": "5s"},
+			check: func(t *testing.T, conf *Config) {
+				if conf.KafkaConfig.Topic != "payments" || conf.KafkaConfig.Broker.Host != "kafka" {
+					t.Errorf("kafka = %+v", conf.KafkaConfig)
+				}
+				if conf.ShutdownTimeout != 5*time.Second {
+					t.Errorf("ShutdownTimeout = %s, want env value", conf.ShutdownTimeout)
+				}
+			},
+		},
+		{
+			name: "escaped comma in env headers",
+			file: `{"kafka": {"broker": {"host": "kafka", "port": "9092"}, "topic": "orders"}, "http_api_url": "http://api:8080"}`,
+			env:  map[string]string{"HTTP_HEADERS": `Accept: a\, b,X-Pair: {{ printf "%s\,%s" .Topic .Key }}`},
+			check: func(t *testing.T, conf *Config) {
+				want := HeaderSpecs{"Accept: a, b", `X-Pair: {{ printf "%s,%s" .Topic .Key }}`}
+				if !reflect.DeepEqual(conf.HttpHeaders, want) {
+					t.Errorf("HttpHeaders = %q, want %q", conf.HttpHeaders, want)
+				}
+			},
+		},
+		{
+			name: "unknown and invalid fields",
+			file: `
+kafka:
+  topik: orders
+  consumer:
+    min_bytes: many
+`,
+			wantErrors: []string{"field topik not found", "cannot unmarshal !!str `many`"},
+		},
+		{
+			name: "pipelines inherit the top level config",
+			file: `
+kafka:
+  broker:
+    host: kafka
+    port: "9092"
+  error_topic: sink-error
+http_method: PUT
+pipelines:
+  - name: orders
+    kafka:
+      topic: orders
+      consumer_group_name: orders-sink
+    http_api_url: http://orders/v1
+  - name: payments
+    kafka:
+      topics: [payments, refunds]
+      error_topic: payments-error
+    http_api_url: http://payments/v1
+    http_method: POST
+`,
+			check: func(t *testing.T, conf *Config) {
+				pipelines := conf.AllPipelines()
+				if len(pipelines) != 2 {
+					t.Fatalf("AllPipelines() = %d pipelines, want 2", len(pipelines))
+				}
+				orders, payments := pipelines[0].Config, pipelines[1].Config
+				if pipelines[0].Name != "orders" || orders.KafkaConfig.Topic != "orders" || orders.KafkaConfig.Broker.Host != "kafka" {
+					t.Errorf("orders = %+v", orders.KafkaConfig)
+				}
+				if *orders.HttpMethod != "PUT" || *orders.KafkaConfig.ErrorTopic != "sink-error" {
+					t.Errorf("orders should inherit method and error topic, got %s %s", *orders.HttpMethod, *orders.KafkaConfig.ErrorTopic)
+				}
+				if *payments.HttpMethod != "POST" || *payments.KafkaConfig.ErrorTopic != "payments-error" || len(payments.KafkaConfig.Topics) != 2 {
+					t.Errorf("payments = %+v", payments)
+				}
+				if *conf.HttpMethod != "PUT" {
+					t.Errorf("top level method = %s, pipelines should not change it", *conf.HttpMethod)
+				}
+			},
+		},
+		{
+			name: "invalid pipelines",
+			file: `
+kafka:
+  broker:
+    host: kafka
+    port: "9092"
+pipelines:
+  - name: orders
+    kafka:
+      topic: orders
+  - name: orders
+    http_api_url: http://orders/v1
+  - kafka:
+      topic: payments
+`,
+			wantErrors: []string{"pipelines[1]: duplicate name", "pipelines[1] orders: exactly one of KAFKA_TOPIC", "pipelines[2]: name is required"},
+		},
+		{
+			name: "unknown pipeline field",
+			file: `
+pipelines:
+  - name: orders
+    kafka:
+      topik: orders
+`,
+			wantErrors: []string{"pipelines[0]: ", "field topik not found"},
+		},
+		{
+			name:       "missing variable",
+			file:       `http_api_url: http://${MISSING_API_HOST}/v1`,
+			wantErrors: []string{"MISSING_API_HOST is not set"},
+		},
+		{
+			name:       "invalid values are validated",
+			file:       `{"kafka": {"broker": {"hosts": ["kafka-1"]}, "topic": "orders"}, "sink": {"type": "ftp"}}`,
+			wantErrors: []string{"KAFKA_BROKER_HOSTS", "SINK_TYPE"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			path := filepath.Join(t.TempDir(), "sink.yaml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			conf, err := Load(path)
+
+			if (err != nil) != (len(tt.wantErrors) > 0) {
+				t.Fatalf("Load() error = %v, wantErrors %v", err, tt.wantErrors)
+			}
+			for _, want := range tt.wantErrors {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %q, should contain %q", err.Error(), want)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, conf)
+			}
+		})
+	}
+}
+
+func TestLoadKafka(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		env        map[string]string
+		wantTopic  string
+		wantHost   string
+		wantErrors []string
+	}{
+		{
+			name:     "env only, the sink settings are not required",
+			env:      map[string]string{"KAFKA_BROKER_HOST": "kafka", "KAFKA_BROKER_PORT": "9092", "SHUTDOWN_TIMEOUT": "0s"},
+			wantHost: "kafka",
+		},
+		{
+			name: "file with invalid sink settings",
+			file: `
+kafka:
+  broker:
+    host: ${KAFKA_HOST:-kafka}
+    port: "9092"
+  topic: orders
+http_method: TRACE
+unknown_field: true
+`,
+			env:       map[string]string{"KAFKA_TOPIC": "payments"},
+			wantHost:  "kafka",
+			wantTopic: "payments",
+		},
+		{
+			name:       "unknown kafka field",
+			file:       "kafka:\n  broker:\n    host: kafka\n    port: \"9092\"\n  partitions: 3\n",
+			wantErrors: []string{"partitions"},
+		},
+		{
+			name:       "missing broker",
+			env:        map[string]string{"KAFKA_TOPIC": "orders"},
+			wantErrors: []string{"KAFKA_BROKER"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			path := ""
+			if tt.file != "" {
+				path = filepath.Join(t.TempDir(), "config.yaml")
+				if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			conf, err := LoadKafka(path)
+			if len(tt.wantErrors) > 0 {
+				if err == nil {
+					t.Fatalf("LoadKafka() error = nil, want %v", tt.wantErrors)
+				}
+				for _, want := range tt.wantErrors {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("LoadKafka() error = %v, want it to contain %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKafka() error = %v", err)
+			}
+			if conf.Broker.Host != tt.wantHost || conf.Topic != tt.wantTopic || conf.TopicRefreshInterval != time.Minute {
+				t.Errorf("LoadKafka() = %+v", conf)
+			}
+		})
+	}
+}
@@ -0,0 +1,666 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// headerName is the RFC 7230 token grammar of a header name.
+var headerName = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// Validate checks the config for invalid values and returns every problem found at once.
+func (c *Config) Validate() error {
+	if len(c.Pipelines) > 0 {
+		return c.validatePipelines()
+	}
+
+	var errs []error
+
+	errs = append(errs, c.KafkaConfig.Broker.validate()...)
+	errs = append(errs, c.KafkaConfig.SASL.validate()...)
+	errs = append(errs, c.KafkaConfig.validateTopics()...)
+	errs = append(errs, c.KafkaConfig.Consumer.validate()...)
+	errs = append(errs, c.DryRun.validate(c.KafkaConfig)...)
+	errs = append(errs, c.validateSink()...)
+	errs = append(errs, c.HttpSuccess.validate()...)
+	errs = append(errs, c.HttpKafkaHeaders.validate()...)
+	errs = append(errs, c.HttpBody.validate(c.KafkaConfig)...)
+	for _, spec := range c.HttpQuery {
+		if _, err := ParseQueryParam(spec); err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_QUERY: %w", err))
+		}
+	}
+	errs = append(errs, c.HttpClient.validate()...)
+	errs = append(errs, c.HttpEndpoints.validate()...)
+	if c.HttpMaxURLLength < 0 {
+		errs = append(errs, fmt.Errorf("HTTP_MAX_URL_LENGTH: must not be negative"))
+	}
+	if c.HttpKeyHeader != nil && *c.HttpKeyHeader != "" && !headerName.MatchString(*c.HttpKeyHeader) {
+		errs = append(errs, fmt.Errorf("HTTP_KEY_HEADER: %q is not a valid header name", *c.HttpKeyHeader))
+	}
+	errs = append(errs, c.Idempotency.validate()...)
+	errs = append(errs, c.Dedup.validate()...)
+	errs = append(errs, c.Success.validate()...)
+
+	if c.Admin.Addr != "" && len(c.Admin.Token) < 16 {
+		errs = append(errs, fmt.Errorf("ADMIN_TOKEN: at least 16 characters are required when ADMIN_ADDR is set"))
+	}
+
+	if c.ReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("CONFIG_RELOAD_INTERVAL: must not be negative"))
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("SHUTDOWN_TIMEOUT: must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validatePipelines validates every pipeline on its own, the top level config is only their defaults.
+func (c *Config) validatePipelines() error {
+	var errs []error
+
+	if c.DryRun.Enabled {
+		errs = append(errs, fmt.Errorf("DRY_RUN_ENABLED: not supported with pipelines"))
+	}
+
+	names := map[string]bool{}
+	for i, pipeline := range c.Pipelines {
+		if pipeline.Name == "" {
+			errs = append(errs, fmt.Errorf("pipelines[%d]: name is required", i))
+		} else if names[pipeline.Name] {
+			errs = append(errs, fmt.Errorf("pipelines[%d]: duplicate name %q", i, pipeline.Name))
+		}
+		names[pipeline.Name] = true
+
+		if len(pipeline.Config.Pipelines) > 0 {
+			errs = append(errs, fmt.Errorf("pipelines[%d]: nested pipelines are not supported", i))
+			continue
+		}
+
+		if err := pipeline.Config.Validate(); err != nil {
+			pipelineErrs := []error{err}
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				pipelineErrs = joined.Unwrap()
+			}
+			for _, e := range pipelineErrs {
+				errs = append(errs, fmt.Errorf("pipelines[%d] %s: %w", i, pipeline.Name, e))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (b KafkaBrokerConfig) validate() []error {
+	var errs []error
+
+	if len(b.Hosts) == 0 && b.Host == "" {
+		errs = append(errs, fmt.Errorf("KAFKA_BROKER_HOSTS or KAFKA_BROKER_HOST is required"))
+	}
+
+	for _, host := range b.Hosts {
+		if !strings.Contains(host, ":") {
+			errs = append(errs, fmt.Errorf("KAFKA_BROKER_HOSTS: %q should be in host:port format", host))
+		}
+	}
+
+	return errs
+}
+
+func (s KafkaSASLConfig) validate() []error {
+	if s.Mechanism == nil {
+		return nil
+	}
+
+	switch strings.ToUpper(*s.Mechanism) {
+	case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+		return nil
+	default:
+		return []error{fmt.Errorf("KAFKA_SASL_MECHANISM: invalid mechanism %q. Allowed mechanisms: PLAIN, SCRAM-SHA-256, SCRAM-SHA-512", *s.Mechanism)}
+	}
+}
+
+func (k KafkaConfig) validateTopics() []error {
+	var errs []error
+
+	subscriptions := 0
+	if k.Topic != "" {
+		subscriptions++
+	}
+	if len(k.Topics) > 0 {
+		subscriptions++
+	}
+	if k.TopicRegex != nil {
+		subscriptions++
+		if _, err := regexp.Compile(*k.TopicRegex); err != nil {
+			errs = append(errs, fmt.Errorf("KAFKA_TOPIC_REGEX: %w", err))
+		}
+		if k.TopicRefreshInterval <= 0 {
+			errs = append(errs, fmt.Errorf("KAFKA_TOPIC_REFRESH_INTERVAL: must be positive"))
+		}
+	}
+
+	if subscriptions != 1 {
+		errs = append(errs, fmt.Errorf("exactly one of KAFKA_TOPIC, KAFKA_TOPICS or KAFKA_TOPIC_REGEX is required"))
+	}
+
+	return errs
+}
+
+func (c KafkaConsumerConfig) validate() []error {
+	var errs []error
+
+	switch c.StartOffset {
+	case "", "earliest", "latest":
+	default:
+		errs = append(errs, fmt.Errorf("KAFKA_CONSUMER_START_OFFSET: invalid value %q, allowed: earliest, latest", c.StartOffset))
+	}
+
+	switch c.RebalanceStrategy {
+	case "", "range", "roundrobin":
+	default:
+		errs = append(errs, fmt.Errorf("KAFKA_CONSUMER_REBALANCE_STRATEGY: invalid value %q, allowed: range, roundrobin", c.RebalanceStrategy))
+	}
+
+	switch c.IsolationLevel {
+	case "", "read_uncommitted", "read_committed":
+	default:
+		errs = append(errs, fmt.Errorf("KAFKA_CONSUMER_ISOLATION_LEVEL: invalid value %q, allowed: read_uncommitted, read_committed", c.IsolationLevel))
+	}
+
+	if c.MinBytes < 0 {
+		errs = append(errs, fmt.Errorf("KAFKA_CONSUMER_MIN_BYTES: must not be negative"))
+	}
+	if c.MaxBytes < 0 {
+		errs = append(errs, fmt.Errorf("KAFKA_CONSUMER_MAX_BYTES: must not be negative"))
+	}
+	maxBytes := c.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = DefaultConsumerMaxBytes
+	}
+	if c.MinBytes > maxBytes {
+		errs = append(errs, fmt.Errorf("KAFKA_CONSUMER_MIN_BYTES: %d is greater than KAFKA_CONSUMER_MAX_BYTES %d", c.MinBytes, maxBytes))
+	}
+
+	if c.MaxWait < 0 || c.SessionTimeout < 0 || c.HeartbeatInterval < 0 || c.CommitInterval < 0 {
+		errs = append(errs, fmt.Errorf("KAFKA_CONSUMER durations must not be negative"))
+	}
+	if c.SessionTimeout > 0 && c.HeartbeatInterval > 0 && c.HeartbeatInterval >= c.SessionTimeout {
+		errs = append(errs, fmt.Errorf("KAFKA_CONSUMER_HEARTBEAT_INTERVAL: %s should be lower than KAFKA_CONSUMER_SESSION_TIMEOUT %s", c.HeartbeatInterval, c.SessionTimeout))
+	}
+
+	return errs
+}
+
+func (d DryRunConfig) validate(k KafkaConfig) []error {
+	var errs []error
+
+	if d.FromOffset == nil {
+		if d.ToOffset != nil {
+			errs = append(errs, fmt.Errorf("DRY_RUN_TO_OFFSET: requires DRY_RUN_FROM_OFFSET"))
+		}
+		return errs
+	}
+
+	if k.Topic == "" {
+		errs = append(errs, fmt.Errorf("DRY_RUN_FROM_OFFSET: requires a single KAFKA_TOPIC"))
+	}
+	if d.ToOffset != nil && *d.ToOffset <= *d.FromOffset {
+		errs = append(errs, fmt.Errorf("DRY_RUN_TO_OFFSET: %d should be greater than DRY_RUN_FROM_OFFSET %d", *d.ToOffset, *d.FromOffset))
+	}
+
+	return errs
+}
+
+func (c *Config) validateSink() []error {
+	var errs []error
+
+	switch c.Sink.Type {
+	case "", "http":
+		errs = append(errs, c.validateHTTP()...)
+	case "stdout":
+	case "grpc":
+		g := c.Sink.Grpc
+		if g.Target == "" {
+			errs = append(errs, fmt.Errorf("SINK_GRPC_TARGET: required for the grpc sink"))
+		}
+		if g.DescriptorSet == "" {
+			errs = append(errs, fmt.Errorf("SINK_GRPC_DESCRIPTOR_SET: required for the grpc sink"))
+		}
+		if !strings.HasPrefix(g.Method, "/") || strings.Count(g.Method, "/") != 2 {
+			errs = append(errs, fmt.Errorf("SINK_GRPC_METHOD: %q should be in /package.Service/Method format", g.Method))
+		}
+		if g.Timeout <= 0 {
+			errs = append(errs, fmt.Errorf("SINK_GRPC_TIMEOUT: must be positive"))
+		}
+		if (g.CertFile == "") != (g.KeyFile == "") {
+			errs = append(errs, fmt.Errorf("SINK_GRPC_CERT_FILE: requires both SINK_GRPC_CERT_FILE and SINK_GRPC_KEY_FILE"))
+		}
+		if g.Insecure && (g.CAFile != "" || g.CertFile != "") {
+			errs = append(errs, fmt.Errorf("SINK_GRPC_INSECURE: TLS files cannot be used with an insecure connection"))
+		}
+		for _, spec := range g.Metadata {
+			if _, _, err := ParseHeaderSpec(spec); err != nil {
+				errs = append(errs, fmt.Errorf("SINK_GRPC_METADATA: %w", err))
+			}
+		}
+	case "file":
+		if c.Sink.FilePath == "" {
+			errs = append(errs, fmt.Errorf("SINK_FILE_PATH: required for the file sink"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("SINK_TYPE: invalid value %q, allowed: http, grpc, file, stdout", c.Sink.Type))
+	}
+
+	return errs
+}
+
+// validateHTTP checks the request settings of the http sink. A templated URL is only
+// checked when rendered, a URL without host is allowed when the endpoints set it.
+func (c *Config) validateHTTP() []error {
+	var errs []error
+
+	if c.HttpApiUrl == "" {
+		errs = append(errs, fmt.Errorf("HTTP_API_URL: required for the http sink"))
+	} else if !strings.Contains(c.HttpApiUrl, "{{") {
+		u, err := url.Parse(c.HttpApiUrl)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_API_URL: %w", err))
+		} else if len(c.HttpEndpoints.URLs) == 0 && (u.Scheme == "" || u.Host == "") {
+			errs = append(errs, fmt.Errorf("HTTP_API_URL: %q should be an absolute URL, or a path when HTTP_ENDPOINTS_URLS is set", c.HttpApiUrl))
+		}
+	}
+
+	if c.HttpMethod != nil {
+		switch strings.ToUpper(*c.HttpMethod) {
+		case "POST", "PUT", "PATCH", "DELETE", "GET", "HEAD":
+		default:
+			errs = append(errs, fmt.Errorf("HTTP_METHOD: invalid method %q. Allowed methods: POST, PUT, PATCH, DELETE, GET, HEAD", *c.HttpMethod))
+		}
+	}
+
+	for _, spec := range c.HttpHeaders {
+		if _, _, err := ParseHeaderSpec(spec); err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_HEADERS: %w", err))
+		}
+	}
+
+	return errs
+}
+
+// ParseHeaderSpec parses a "Name: value" spec, the value template itself is parsed by the sink.
+func ParseHeaderSpec(spec string) (string, string, error) {
+	name, value, ok := strings.Cut(spec, ":")
+	name = strings.TrimSpace(name)
+	if !ok || !headerName.MatchString(name) {
+		return "", "", fmt.Errorf("%q should be \"Name: value\" with a valid header name", spec)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+func (i IdempotencyConfig) validate() []error {
+	if i.Header == "" {
+		return nil
+	}
+
+	var errs []error
+	if !headerName.MatchString(i.Header) {
+		errs = append(errs, fmt.Errorf("IDEMPOTENCY_HEADER: %q is not a valid header name", i.Header))
+	}
+
+	switch i.Source {
+	case "", "coordinates", "hash":
+	case "field":
+		if i.Field == "" {
+			errs = append(errs, fmt.Errorf("IDEMPOTENCY_FIELD: required for the field source"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("IDEMPOTENCY_SOURCE: invalid source %q. Allowed sources: coordinates, hash, field", i.Source))
+	}
+
+	return errs
+}
+
+func (d DedupConfig) validate() []error {
+	var errs []error
+
+	switch d.Source {
+	case "":
+		return nil
+	case "key":
+	case "header":
+		if d.Header == "" {
+			errs = append(errs, fmt.Errorf("DEDUP_HEADER: required for the header source"))
+		}
+	case "field":
+		if d.Field == "" {
+			errs = append(errs, fmt.Errorf("DEDUP_FIELD: required for the field source"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("DEDUP_SOURCE: invalid source %q. Allowed sources: key, header, field", d.Source))
+	}
+
+	if d.Window <= 0 {
+		errs = append(errs, fmt.Errorf("DEDUP_WINDOW: must be positive"))
+	}
+	if d.Size <= 0 {
+		errs = append(errs, fmt.Errorf("DEDUP_SIZE: must be positive"))
+	}
+
+	return errs
+}
+
+func (s SuccessConfig) validate() []error {
+	var errs []error
+
+	for _, name := range s.ResponseHeaders {
+		if !headerName.MatchString(name) {
+			errs = append(errs, fmt.Errorf("SUCCESS_RESPONSE_HEADERS: %q is not a valid header name", name))
+		}
+	}
+	if !s.Record && (len(s.ResponseHeaders) > 0 || s.IncludeRequestBody) {
+		errs = append(errs, fmt.Errorf("SUCCESS_RECORD: required by SUCCESS_RESPONSE_HEADERS and SUCCESS_INCLUDE_REQUEST_BODY"))
+	}
+	if s.Record && len(s.ValueMappings) > 0 {
+		errs = append(errs, fmt.Errorf("SUCCESS_VALUE_MAPPINGS: cannot be used with SUCCESS_RECORD"))
+	}
+
+	for _, spec := range s.HeaderMappings {
+		if _, err := ParseResponseMapping(spec); err != nil {
+			errs = append(errs, fmt.Errorf("SUCCESS_HEADER_MAPPINGS: %w", err))
+		}
+	}
+	for _, spec := range s.ValueMappings {
+		if _, err := ParseResponseMapping(spec); err != nil {
+			errs = append(errs, fmt.Errorf("SUCCESS_VALUE_MAPPINGS: %w", err))
+		}
+	}
+	if s.Key != "" {
+		if _, err := ParseMappingSource(s.Key); err != nil {
+			errs = append(errs, fmt.Errorf("SUCCESS_KEY: %w", err))
+		}
+	}
+
+	return errs
+}
+
+// MappingSource is where a response mapping reads its value, Header is set for
+// header.<name> and Path for body.<field path>.
+type MappingSource struct {
+	Header string
+	Path   []string
+}
+
+// ParseMappingSource parses body.<field path> or header.<name>.
+func ParseMappingSource(source string) (MappingSource, error) {
+	if name, ok := strings.CutPrefix(source, "header."); ok && name != "" {
+		return MappingSource{Header: name}, nil
+	}
+	if path, ok := strings.CutPrefix(source, "body."); ok && path != "" {
+		return MappingSource{Path: strings.Split(path, ".")}, nil
+	}
+	return MappingSource{}, fmt.Errorf("%q should be body.<field path> or header.<name>", source)
+}
+
+// ResponseMapping writes the value of Source as Name, see SuccessConfig.HeaderMappings.
+type ResponseMapping struct {
+	Name   string
+	Source MappingSource
+}
+
+// ParseResponseMapping parses a "name: source" spec.
+func ParseResponseMapping(spec string) (ResponseMapping, error) {
+	name, source, ok := strings.Cut(spec, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return ResponseMapping{}, fmt.Errorf("%q should be in \"name: source\" format", spec)
+	}
+
+	src, err := ParseMappingSource(strings.TrimSpace(source))
+	if err != nil {
+		return ResponseMapping{}, fmt.Errorf("%q source: %w", spec, err)
+	}
+	return ResponseMapping{Name: name, Source: src}, nil
+}
+
+func (h HttpSuccessConfig) validate() []error {
+	var errs []error
+
+	for _, code := range h.StatusCodes {
+		if _, _, err := ParseStatusRange(code); err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_SUCCESS_STATUS_CODES: %w", err))
+		}
+	}
+	for _, predicate := range h.Body {
+		if _, err := ParseBodyPredicate(predicate); err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_SUCCESS_BODY: %w", err))
+		}
+	}
+	for _, name := range h.Headers {
+		if !headerName.MatchString(name) {
+			errs = append(errs, fmt.Errorf("HTTP_SUCCESS_HEADERS: %q is not a valid header name", name))
+		}
+	}
+
+	return errs
+}
+
+// Operators of BodyPredicate.
+const (
+	PredicateEqual    = "=="
+	PredicateNotEqual = "!="
+	PredicateExists   = "exists"
+)
+
+// BodyPredicate is a condition on a field of the JSON response, see HttpSuccessConfig.Body.
+type BodyPredicate struct {
+	Path  []string
+	Op    string
+	Value interface{}
+}
+
+// ParseBodyPredicate parses "<field path> == <value>", "<field path> != <value>" or "<field path>".
+func ParseBodyPredicate(spec string) (BodyPredicate, error) {
+	predicate := BodyPredicate{Op: PredicateExists}
+	path := strings.TrimSpace(spec)
+	for _, op := range []string{PredicateEqual, PredicateNotEqual} {
+		if field, value, ok := strings.Cut(spec, op); ok {
+			path = strings.TrimSpace(field)
+			predicate.Op = op
+
+			value = strings.TrimSpace(value)
+			if err := json.Unmarshal([]byte(value), &predicate.Value); err != nil {
+				predicate.Value = value
+			}
+			break
+		}
+	}
+
+	if path == "" || strings.ContainsAny(path, " =!") {
+		return BodyPredicate{}, fmt.Errorf("%q should be \"<field path> == <value>\", \"<field path> != <value>\" or \"<field path>\"", spec)
+	}
+	predicate.Path = strings.Split(path, ".")
+	return predicate, nil
+}
+
+// ParseStatusRange parses a status code, e.g. 200, or an inclusive range, e.g. 200-299.
+func ParseStatusRange(spec string) (int, int, error) {
+	from, to, isRange := strings.Cut(strings.TrimSpace(spec), "-")
+	if !isRange {
+		to = from
+	}
+
+	min, errMin := strconv.Atoi(strings.TrimSpace(from))
+	max, errMax := strconv.Atoi(strings.TrimSpace(to))
+	if errMin != nil || errMax != nil || min < 100 || max > 599 || min > max {
+		return 0, 0, fmt.Errorf("%q should be a status code or a range of status codes, e.g. 200-299", spec)
+	}
+	return min, max, nil
+}
+
+func (h HttpKafkaHeadersConfig) validate() []error {
+	var errs []error
+
+	for _, spec := range h.Rename {
+		if _, _, err := ParseHeaderRename(spec); err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_KAFKA_HEADERS_RENAME: %w", err))
+		}
+	}
+	for _, spec := range h.Templates {
+		if _, _, err := ParseHeaderTemplate(spec); err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_KAFKA_HEADERS_TEMPLATES: %w", err))
+		}
+	}
+	if h.Prefix != "" && !headerName.MatchString(h.Prefix) {
+		errs = append(errs, fmt.Errorf("HTTP_KAFKA_HEADERS_PREFIX: %q is not valid in a header name", h.Prefix))
+	}
+
+	switch h.Binary {
+	case "", "base64", "drop":
+	default:
+		errs = append(errs, fmt.Errorf("HTTP_KAFKA_HEADERS_BINARY: invalid value %q. Allowed values: base64, drop", h.Binary))
+	}
+
+	return errs
+}
+
+// ParseHeaderRename parses a "kafka-name: HTTP-Name" spec.
+func ParseHeaderRename(spec string) (string, string, error) {
+	from, to, ok := strings.Cut(spec, ":")
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if !ok || from == "" || !headerName.MatchString(to) {
+		return "", "", fmt.Errorf("%q should be \"kafka-name: HTTP-Name\" with a valid HTTP header name", spec)
+	}
+	return from, to, nil
+}
+
+// ParseHeaderTemplate parses a "kafka-name: template" spec, the template itself is parsed by the sink.
+func ParseHeaderTemplate(spec string) (string, string, error) {
+	name, tmpl, ok := strings.Cut(spec, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("%q should be \"kafka-name: template\"", spec)
+	}
+	return name, strings.TrimSpace(tmpl), nil
+}
+
+// QueryParam adds the field at Path of the decoded JSON value as the query parameter Name, see Config.HttpQuery.
+type QueryParam struct {
+	Name string
+	Path []string
+}
+
+// ParseQueryParam parses a "param: field path" spec.
+func ParseQueryParam(spec string) (QueryParam, error) {
+	name, path, ok := strings.Cut(spec, ":")
+	name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+	if !ok || name == "" || path == "" {
+		return QueryParam{}, fmt.Errorf("%q should be \"param: field path\"", spec)
+	}
+	return QueryParam{Name: name, Path: strings.Split(path, ".")}, nil
+}
+
+func (b HttpBodyConfig) validate(k KafkaConfig) []error {
+	var errs []error
+
+	switch b.Encoding {
+	case "", "json", "form", "ndjson", "raw":
+	case "avro":
+		if k.SchemaRegistryUrl == nil {
+			errs = append(errs, fmt.Errorf("HTTP_BODY_ENCODING: avro requires KAFKA_SCHEMA_REGISTRY_URL"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("HTTP_BODY_ENCODING: invalid encoding %q. Allowed encodings: json, form, ndjson, raw, avro", b.Encoding))
+	}
+
+	switch b.Compression {
+	case "", "gzip", "zstd":
+	default:
+		errs = append(errs, fmt.Errorf("HTTP_BODY_COMPRESSION: invalid compression %q. Allowed compressions: gzip, zstd", b.Compression))
+	}
+
+	return errs
+}
+
+func (h HttpClientConfig) validate() []error {
+	var errs []error
+
+	durations := []struct {
+		env   string
+		value time.Duration
+	}{
+		{"HTTP_CLIENT_TIMEOUT", h.Timeout},
+		{"HTTP_CLIENT_CONNECT_TIMEOUT", h.ConnectTimeout},
+		{"HTTP_CLIENT_TLS_HANDSHAKE_TIMEOUT", h.TLSHandshakeTimeout},
+		{"HTTP_CLIENT_RESPONSE_HEADER_TIMEOUT", h.ResponseHeaderTimeout},
+		{"HTTP_CLIENT_IDLE_CONN_TIMEOUT", h.IdleConnTimeout},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative", d.env))
+		}
+	}
+
+	counts := []struct {
+		env   string
+		value int
+	}{
+		{"HTTP_CLIENT_MAX_IDLE_CONNS", h.MaxIdleConns},
+		{"HTTP_CLIENT_MAX_IDLE_CONNS_PER_HOST", h.MaxIdleConnsPerHost},
+		{"HTTP_CLIENT_MAX_CONNS_PER_HOST", h.MaxConnsPerHost},
+	}
+	for _, c := range counts {
+		if c.value < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative", c.env))
+		}
+	}
+
+	if h.Proxy != "" {
+		if u, err := url.Parse(h.Proxy); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			errs = append(errs, fmt.Errorf("HTTP_CLIENT_PROXY: %q should be an http, https or socks5 URL", h.Proxy))
+		}
+	}
+
+	return errs
+}
+
+func (e HttpEndpointsConfig) validate() []error {
+	if len(e.URLs) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, raw := range e.URLs {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("HTTP_ENDPOINTS_URLS: %q should be an http or https URL", raw))
+			continue
+		}
+		if (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("HTTP_ENDPOINTS_URLS: %q should be a base URL without path or query", raw))
+		}
+	}
+
+	switch e.Strategy {
+	case "", "round_robin", "key_hash":
+	default:
+		errs = append(errs, fmt.Errorf("HTTP_ENDPOINTS_STRATEGY: invalid strategy %q. Allowed strategies: round_robin, key_hash", e.Strategy))
+	}
+
+	if e.EjectAfter < 0 {
+		errs = append(errs, fmt.Errorf("HTTP_ENDPOINTS_EJECT_AFTER: must not be negative"))
+	}
+	if e.EjectFor < 0 {
+		errs = append(errs, fmt.Errorf("HTTP_ENDPOINTS_EJECT_FOR: must not be negative"))
+	}
+
+	return errs
+}
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	invalidHeaderName := "kafka key"
+	tests := []struct {
+		name       string
+		conf       Config
+		wantErrors []string
+	}{
+		{
+			name: "valid single broker",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				HttpApiUrl: "http://api:8080/v1/orders",
+			},
+		},
+		{
+			name: "valid broker list with tuning",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Hosts: []string{"kafka-1:9092", "kafka-2:9092"}},
+					Topics: []string{"orders", "payments"},
+					Consumer: KafkaConsumerConfig{
+						StartOffset:       "latest",
+						MinBytes:          1,
+						MaxBytes:          1e6,
+						SessionTimeout:    30 * time.Second,
+						HeartbeatInterval: 3 * time.Second,
+						RebalanceStrategy: "roundrobin",
+						IsolationLevel:    "read_committed",
+					},
+				},
+				HttpApiUrl: "http://api:8080/v1/orders",
+			},
+		},
+		{
+			name: "valid topic regex",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker:               KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					TopicRegex:           stringPtr(`^orders\..+`),
+					TopicRefreshInterval: time.Minute,
+				},
+				HttpApiUrl: "http://api:8080/v1/orders",
+			},
+		},
+		{
+			name: "invalid http request settings",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					SASL:   KafkaSASLConfig{Mechanism: stringPtr("GSSAPI")},
+					Topic:  "orders",
+				},
+				HttpApiUrl:  "api/v1/orders",
+				HttpMethod:  stringPtr("FOO"),
+				HttpHeaders: HeaderSpecs{"Bad Name: x"},
+			},
+			wantErrors: []string{"KAFKA_SASL_MECHANISM", "HTTP_API_URL", "HTTP_METHOD", "HTTP_HEADERS"},
+		},
+		{
+			name:       "missing http api url",
+			conf:       Config{KafkaConfig: KafkaConfig{Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"}, Topic: "orders"}},
+			wantErrors: []string{"HTTP_API_URL: required"},
+		},
+		{
+			name:       "missing broker and topic",
+			conf:       Config{},
+			wantErrors: []string{"KAFKA_BROKER_HOSTS or KAFKA_BROKER_HOST", "exactly one of KAFKA_TOPIC"},
+		},
+		{
+			name: "topic and invalid regex",
+			conf: Config{KafkaConfig: KafkaConfig{
+				Broker:               KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+				Topic:                "orders",
+				TopicRegex:           stringPtr(`orders(`),
+				TopicRefreshInterval: time.Minute,
+			}},
+			wantErrors: []string{"KAFKA_TOPIC_REGEX", "exactly one of KAFKA_TOPIC"},
+		},
+		{
+			name: "every invalid field is reported",
+			conf: Config{KafkaConfig: KafkaConfig{
+				Broker: KafkaBrokerConfig{Hosts: []string{"kafka-1"}},
+				Topic:  "orders",
+				Consumer: KafkaConsumerConfig{
+					StartOffset:       "beginning",
+					MinBytes:          10,
+					MaxBytes:          1,
+					SessionTimeout:    time.Second,
+					HeartbeatInterval: 2 * time.Second,
+					RebalanceStrategy: "sticky",
+					IsolationLevel:    "serializable",
+				},
+			}},
+			wantErrors: []string{
+				"KAFKA_BROKER_HOSTS",
+				"KAFKA_CONSUMER_START_OFFSET",
+				"KAFKA_CONSUMER_MIN_BYTES",
+				"KAFKA_CONSUMER_HEARTBEAT_INTERVAL",
+				"KAFKA_CONSUMER_REBALANCE_STRATEGY",
+				"KAFKA_CONSUMER_ISOLATION_LEVEL",
+			},
+		},
+		{
+			name: "min bytes above the default max bytes",
+			conf: Config{KafkaConfig: KafkaConfig{
+				Broker:   KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+				Topic:    "orders",
+				Consumer: KafkaConsumerConfig{MinBytes: 2e6},
+			}},
+			wantErrors: []string{"KAFKA_CONSUMER_MIN_BYTES"},
+		},
+		{
+			name: "incomplete grpc sink",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				Sink: SinkConfig{Type: "grpc", Grpc: GrpcSinkConfig{
+					Target:  "orders:9090",
+					Method:  "orders.v1.OrderService.CreateOrder",
+					Timeout: time.Second,
+				}},
+			},
+			wantErrors: []string{"SINK_GRPC_DESCRIPTOR_SET", "SINK_GRPC_METHOD"},
+		},
+		{
+			name: "invalid grpc tls",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				Sink: SinkConfig{Type: "grpc", Grpc: GrpcSinkConfig{
+					Target:        "orders:9090",
+					Method:        "/orders.v1.OrderService/CreateOrder",
+					DescriptorSet: "orders.pb",
+					Timeout:       time.Second,
+					Insecure:      true,
+					CAFile:        "ca.pem",
+					CertFile:      "client.pem",
+				}},
+			},
+			wantErrors: []string{"SINK_GRPC_CERT_FILE", "SINK_GRPC_INSECURE"},
+		},
+		{
+			name: "file sink without path",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				Sink: SinkConfig{Type: "file"},
+			},
+			wantErrors: []string{"SINK_FILE_PATH"},
+		},
+		{
+			name: "invalid idempotency",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				Idempotency: IdempotencyConfig{Header: "Idempotency Key", Source: "field"},
+			},
+			wantErrors: []string{"IDEMPOTENCY_HEADER", "IDEMPOTENCY_FIELD"},
+		},
+		{
+			name: "invalid dedup",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				Dedup: DedupConfig{Source: "header", Window: -time.Minute, Size: 10},
+			},
+			wantErrors: []string{"DEDUP_HEADER", "DEDUP_WINDOW"},
+		},
+		{
+			name: "success record options without record",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				Success: SuccessConfig{ResponseHeaders: []string{"Location", "Bad Header"}},
+			},
+			wantErrors: []string{"SUCCESS_RESPONSE_HEADERS", "SUCCESS_RECORD"},
+		},
+		{
+			name: "invalid http success criteria",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				HttpSuccess: HttpSuccessConfig{
+					StatusCodes: []string{"200", "299-200"},
+					Body:        []string{"success == true", "== false"},
+					Headers:     []string{"X Request"},
+				},
+			},
+			wantErrors: []string{"HTTP_SUCCESS_STATUS_CODES", "HTTP_SUCCESS_BODY", "HTTP_SUCCESS_HEADERS"},
+		},
+		{
+			name: "invalid kafka header mapping",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				HttpKeyHeader: &invalidHeaderName,
+				HttpKafkaHeaders: HttpKafkaHeadersConfig{
+					Rename: []string{"tenant: X Tenant"},
+					Binary: "hex",
+				},
+			},
+			wantErrors: []string{"HTTP_KEY_HEADER", "HTTP_KAFKA_HEADERS_RENAME", "HTTP_KAFKA_HEADERS_BINARY"},
+		},
+		{
+			name: "invalid http body",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				HttpBody: HttpBodyConfig{Encoding: "avro", Compression: "br"},
+			},
+			wantErrors: []string{"KAFKA_SCHEMA_REGISTRY_URL", "HTTP_BODY_COMPRESSION"},
+		},
+		{
+			name: "invalid http query",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				HttpQuery:        []string{"id: order.id", "order.status"},
+				HttpMaxURLLength: -1,
+			},
+			wantErrors: []string{"HTTP_QUERY", "HTTP_MAX_URL_LENGTH"},
+		},
+		{
+			name: "invalid http client",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				HttpClient: HttpClientConfig{
+					Timeout:             -time.Second,
+					MaxIdleConnsPerHost: -1,
+					Proxy:               "ftp://proxy:21",
+				},
+			},
+			wantErrors: []string{"HTTP_CLIENT_TIMEOUT", "HTTP_CLIENT_MAX_IDLE_CONNS_PER_HOST", "HTTP_CLIENT_PROXY"},
+		},
+		{
+			name: "invalid http endpoints",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				HttpEndpoints: HttpEndpointsConfig{
+					URLs:       []string{"http://api-1:8080", "api-2:8080", "http://api-3:8080/v1"},
+					Strategy:   "least_in_flight",
+					EjectAfter: -1,
+				},
+			},
+			wantErrors: []string{"HTTP_ENDPOINTS_URLS", "HTTP_ENDPOINTS_STRATEGY", "HTTP_ENDPOINTS_EJECT_AFTER"},
+		},
+		{
+			name: "invalid success mappings",
+			conf: Config{
+				KafkaConfig: KafkaConfig{
+					Broker: KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+					Topic:  "orders",
+				},
+				Success: SuccessConfig{
+					HeaderMappings: []string{"order-id: data.id"},
+					ValueMappings:  []string{"body.data.id"},
+					Key:            "header.",
+				},
+			},
+			wantErrors: []string{"SUCCESS_HEADER_MAPPINGS", "SUCCESS_VALUE_MAPPINGS", "SUCCESS_KEY"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// defaults set by envconfig
+			tt.conf.ShutdownTimeout = 30 * time.Second
+
+			err := tt.conf.Validate()
+
+			if (err != nil) != (len(tt.wantErrors) > 0) {
+				t.Errorf("Validate() error = %v, wantErrors %v", err, tt.wantErrors)
+				return
+			}
+
+			for _, want := range tt.wantErrors {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %q, should contain %q", err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseResponseMapping(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    ResponseMapping
+		wantErr bool
+	}{
+		{spec: "order-id: body.data.id", want: ResponseMapping{Name: "order-id", Source: MappingSource{Path: []string{"data", "id"}}}},
+		{spec: " location : header.Location", want: ResponseMapping{Name: "location", Source: MappingSource{Header: "Location"}}},
+		{spec: "body.data.id", wantErr: true},
+		{spec: ": body.id", wantErr: true},
+		{spec: "order-id: data.id", wantErr: true},
+		{spec: "order-id: header.", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseResponseMapping(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseResponseMapping(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseResponseMapping(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
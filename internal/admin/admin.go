@@ -0,0 +1,270 @@
+// Package admin serves the token protected admin API to pause, resume and
+// inspect the running pipelines.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+	"github.com/urbanindo/go-kafka-http-sink/internal/pipeline"
+	"github.com/urbanindo/go-kafka-http-sink/internal/processor"
+	"go.uber.org/zap"
+)
+
+// Pipeline is a running pipeline registered to the admin API.
+type Pipeline struct {
+	Name       string
+	Conf       *config.Config
+	Dialer     *kafka.Dialer
+	Transport  *kafka.Transport
+	Controller *pipeline.Controller
+	// Endpoints reports the health of the sink endpoints, nil when the sink has none.
+	Endpoints func() []processor.EndpointState
+}
+
+// topics returns the subscribed topics, the regex reader knows the currently matched ones.
+func (p *Pipeline) topics() []string {
+	if reader, ok := p.Controller.Reader().(interface{ Topics() []string }); ok {
+		return reader.Topics()
+	}
+	if p.Conf.KafkaConfig.Topic != "" {
+		return []string{p.Conf.KafkaConfig.Topic}
+	}
+	return p.Conf.KafkaConfig.Topics
+}
+
+// Server routes the admin requests:
+//
+//	GET  /pipelines                        list the pipelines
+//	GET  /pipelines/{name}                 group members, assignments, offsets and ejected endpoints
+//	POST /pipelines/{name}/pause           stop delivery, waits for the in-flight message
+//	POST /pipelines/{name}/resume          continue delivery
+//	POST /pipelines/{name}/reset-offsets   {"timestamp": "2024-01-02T03:04:05Z"}, requires pause
+//
+// Every request needs the "Authorization: Bearer <ADMIN_TOKEN>" header.
+type Server struct {
+	token string
+	logr  *zap.Logger
+
+	mu        sync.RWMutex
+	pipelines map[string]*Pipeline
+}
+
+func NewServer(token string, logr *zap.Logger) *Server {
+	return &Server{token: token, logr: logr, pipelines: map[string]*Pipeline{}}
+}
+
+// Register adds the pipeline to the admin API.
+func (s *Server) Register(p *Pipeline) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pipelines[p.Name] = p
+}
+
+type pipelineSummary struct {
+	Name   string   `json:"name"`
+	Paused bool     `json:"paused"`
+	Topics []string `json:"topics"`
+	Group  string   `json:"group"`
+}
+
+type pipelineStatus struct {
+	pipelineSummary
+	Status *kafkaclient.GroupStatus `json:"status,omitempty"`
+	Error  string                   `json:"error,omitempty"`
+	// Endpoints is the state of HTTP_ENDPOINTS_URLS, an ejected endpoint is the
+	// circuit breaker of that endpoint being open.
+	Endpoints []processor.EndpointState `json:"endpoints,omitempty"`
+}
+
+type resetRequest struct {
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type resetResponse struct {
+	Timestamp time.Time             `json:"timestamp"`
+	Offsets   []resetPartitionReply `json:"offsets"`
+}
+
+type resetPartitionReply struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, errors.New("invalid or missing admin token"))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "pipelines" || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		s.list(w)
+		return
+	}
+
+	s.mu.RLock()
+	p, ok := s.pipelines[parts[1]]
+	s.mu.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("pipeline %q not found", parts[1]))
+		return
+	}
+
+	action := ""
+	if len(parts) == 3 {
+		action = parts[2]
+	}
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		s.status(w, r, p)
+	case action == "pause" && r.Method == http.MethodPost:
+		s.pause(w, r, p)
+	case action == "resume" && r.Method == http.MethodPost:
+		p.Controller.Resume()
+		s.logr.Info("pipeline resumed by admin API", zap.String("pipeline", p.Name))
+		writeJSON(w, http.StatusOK, summarize(p))
+	case action == "reset-offsets" && r.Method == http.MethodPost:
+		s.resetOffsets(w, r, p)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func (s *Server) list(w http.ResponseWriter) {
+	s.mu.RLock()
+	summaries := make([]pipelineSummary, 0, len(s.pipelines))
+	for _, p := range s.pipelines {
+		summaries = append(summaries, summarize(p))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request, p *Pipeline) {
+	status := pipelineStatus{pipelineSummary: summarize(p)}
+	group, err := kafkaclient.ReadGroupStatus(r.Context(), p.Conf.KafkaConfig, p.Dialer, p.Transport, status.Topics)
+	if err != nil {
+		status.Error = err.Error()
+	}
+	status.Status = group
+	if p.Endpoints != nil {
+		status.Endpoints = p.Endpoints()
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) pause(w http.ResponseWriter, r *http.Request, p *Pipeline) {
+	if err := p.Controller.Pause(r.Context()); err != nil {
+		writeError(w, http.StatusGatewayTimeout, fmt.Errorf("paused, but the in-flight message is not done yet: %w", err))
+		return
+	}
+
+	s.logr.Info("pipeline paused by admin API", zap.String("pipeline", p.Name))
+	writeJSON(w, http.StatusOK, summarize(p))
+}
+
+// resetOffsets commits the offsets at the timestamp for every partition and restarts
+// the reader, so the group continues from there once resumed. Other instances of the
+// same group should be paused too, otherwise they may commit over the reset offsets.
+func (s *Server) resetOffsets(w http.ResponseWriter, r *http.Request, p *Pipeline) {
+	if !p.Controller.Paused() {
+		writeError(w, http.StatusConflict, fmt.Errorf("pause the pipeline before resetting offsets: %w", pipeline.ErrNotPaused))
+		return
+	}
+
+	var req resetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Timestamp.IsZero() {
+		writeError(w, http.StatusBadRequest, errors.New(`body should be {"timestamp": "<RFC3339 time>"}`))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	res := resetResponse{Timestamp: req.Timestamp, Offsets: []resetPartitionReply{}}
+	var msgs []kafka.Message
+	for _, topic := range p.topics() {
+		partitions, err := kafkaclient.ReadPartitionOffsets(ctx, p.Conf.KafkaConfig, p.Dialer, topic)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		for _, partition := range partitions {
+			offset, err := kafkaclient.ReadOffsetAt(ctx, p.Conf.KafkaConfig, p.Dialer, topic, partition.Partition, req.Timestamp)
+			if err != nil {
+				writeError(w, http.StatusBadGateway, err)
+				return
+			}
+			// The committed offset is the offset of the message after the committed one
+			msgs = append(msgs, kafka.Message{Topic: topic, Partition: partition.Partition, Offset: offset - 1})
+			res.Offsets = append(res.Offsets, resetPartitionReply{Topic: topic, Partition: partition.Partition, Offset: offset})
+		}
+	}
+
+	if err := p.Controller.Commit(ctx, msgs...); err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to commit offsets: %w", err))
+		return
+	}
+	if err := p.Controller.Restart(); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("offsets committed but failed to restart reader: %w", err))
+		return
+	}
+
+	s.logr.Info(
+		"pipeline offsets reset by admin API",
+		zap.String("pipeline", p.Name),
+		zap.Time("timestamp", req.Timestamp),
+		zap.Any("offsets", res.Offsets),
+	)
+	writeJSON(w, http.StatusOK, res)
+}
+
+func summarize(p *Pipeline) pipelineSummary {
+	return pipelineSummary{
+		Name:   p.Name,
+		Paused: p.Controller.Paused(),
+		Topics: p.topics(),
+		Group:  p.Conf.KafkaConfig.ConsumerGroupName,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
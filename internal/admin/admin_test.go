@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+	"github.com/urbanindo/go-kafka-http-sink/internal/pipeline"
+	"go.uber.org/zap"
+)
+
+func TestServer(t *testing.T) {
+	const token = "0123456789abcdef"
+	broker := kafkaclient.NewMemoryBroker()
+	controller, err := pipeline.NewController(func() (kafkaclient.Consumer, error) {
+		return broker.Reader("orders-sink", "orders"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(token, zap.NewNop())
+	server.Register(&Pipeline{
+		Name:       "orders",
+		Conf:       &config.Config{KafkaConfig: config.KafkaConfig{Topic: "orders", ConsumerGroupName: "orders-sink"}},
+		Controller: controller,
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "missing token",
+			method:   http.MethodGet,
+			path:     "/pipelines",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "wrong token",
+			method:   http.MethodGet,
+			path:     "/pipelines",
+			token:    "fedcba9876543210",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "list",
+			method:   http.MethodGet,
+			path:     "/pipelines",
+			token:    token,
+			wantCode: http.StatusOK,
+			wantBody: `[{"name":"orders","paused":false,"topics":["orders"],"group":"orders-sink"}]`,
+		},
+		{
+			name:     "reset requires pause",
+			method:   http.MethodPost,
+			path:     "/pipelines/orders/reset-offsets",
+			token:    token,
+			body:     `{"timestamp":"2024-01-02T03:04:05Z"}`,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:     "pause",
+			method:   http.MethodPost,
+			path:     "/pipelines/orders/pause",
+			token:    token,
+			wantCode: http.StatusOK,
+			wantBody: `"paused":true`,
+		},
+		{
+			name:     "reset without timestamp",
+			method:   http.MethodPost,
+			path:     "/pipelines/orders/reset-offsets",
+			token:    token,
+			body:     `{}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "resume",
+			method:   http.MethodPost,
+			path:     "/pipelines/orders/resume",
+			token:    token,
+			wantCode: http.StatusOK,
+			wantBody: `"paused":false`,
+		},
+		{
+			name:     "unknown pipeline",
+			method:   http.MethodPost,
+			path:     "/pipelines/payments/pause",
+			token:    token,
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+
+			server.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, should contain %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Package dedup remembers the keys of delivered messages for a time window,
+// so a duplicate produced within the window can be dropped.
+package dedup
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps the delivered keys. MemoryStore is used by default, a persistent
+// implementation, e.g. backed by Redis, keeps the window across restarts and
+// shares it between the instances of the consumer group.
+type Store interface {
+	// Contains tells whether the key was added within the window.
+	Contains(ctx context.Context, key string) (bool, error)
+	// Add remembers the key for the window.
+	Add(ctx context.Context, key string) error
+	Close() error
+}
+
+// MemoryStore is an LRU of at most size keys that expire after ttl.
+type MemoryStore struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type entry struct {
+	key     string
+	expires time.Time
+}
+
+func NewMemoryStore(size int, ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (s *MemoryStore) Contains(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return false, nil
+	}
+	if s.now().After(elem.Value.(*entry).expires) {
+		s.remove(elem)
+		return false, nil
+	}
+	return true, nil
+}
+
+// Add remembers the key, evicting the least recently added key when the store is full.
+func (s *MemoryStore) Add(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires := s.now().Add(s.ttl)
+	if elem, ok := s.entries[key]; ok {
+		elem.Value.(*entry).expires = expires
+		s.order.MoveToFront(elem)
+		return nil
+	}
+
+	s.entries[key] = s.order.PushFront(&entry{key: key, expires: expires})
+	for s.order.Len() > s.size {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+// Len returns the number of keys kept, including the expired ones not evicted yet.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.entries, elem.Value.(*entry).key)
+}
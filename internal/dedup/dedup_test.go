@@ -0,0 +1,53 @@
+package dedup
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	store := NewMemoryStore(2, time.Minute)
+	store.now = func() time.Time { return now }
+
+	store.Add(ctx, "a")
+	store.Add(ctx, "b")
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		add     string
+		key     string
+		want    bool
+	}{
+		{name: "added key", key: "a", want: true},
+		{name: "unknown key", key: "c", want: false},
+		{name: "least recently added key is evicted", add: "c", key: "a", want: false},
+		{name: "recent key is kept", key: "b", want: true},
+		{name: "expired key", advance: 2 * time.Minute, key: "c", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			if tt.add != "" {
+				store.Add(ctx, tt.add)
+			}
+
+			got, err := store.Contains(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("Contains() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Contains(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	if store.Len() != 1 {
+		t.Errorf("Len() = %d, want 1 after the expired key is removed", store.Len())
+	}
+}
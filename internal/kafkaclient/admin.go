@@ -0,0 +1,222 @@
+package kafkaclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+// PartitionOffsets is the offset range of a partition, First is the oldest
+// available offset and Last is the offset of the next produced message.
+type PartitionOffsets struct {
+	Partition int   `json:"partition"`
+	First     int64 `json:"first"`
+	Last      int64 `json:"last"`
+}
+
+// Dial connects to the first reachable broker.
+func Dial(ctx context.Context, conf config.KafkaConfig, dialer *kafka.Dialer) (*kafka.Conn, error) {
+	var err error
+	for _, broker := range Brokers(conf) {
+		var conn *kafka.Conn
+		conn, err = dialer.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to connect to kafka: %w", err)
+}
+
+// ReadPartitionOffsets returns the offset range of every partition of the topic, sorted by partition.
+func ReadPartitionOffsets(ctx context.Context, conf config.KafkaConfig, dialer *kafka.Dialer, topic string) ([]PartitionOffsets, error) {
+	conn, err := Dial(ctx, conf, dialer)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read partitions of topic %s: %w", topic, err)
+	}
+
+	offsets := make([]PartitionOffsets, 0, len(partitions))
+	for _, partition := range partitions {
+		leader, err := dialLeader(ctx, dialer, partition)
+		if err != nil {
+			return nil, err
+		}
+
+		first, last, err := leader.ReadOffsets()
+		leader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read offsets of %s/%d: %w", topic, partition.ID, err)
+		}
+
+		offsets = append(offsets, PartitionOffsets{
+			Partition: partition.ID,
+			First:     first,
+			Last:      last,
+		})
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i].Partition < offsets[j].Partition })
+
+	return offsets, nil
+}
+
+// ReadOffsetAt returns the first offset of the partition with a timestamp at or after t,
+// or the last offset when no such message exists.
+func ReadOffsetAt(ctx context.Context, conf config.KafkaConfig, dialer *kafka.Dialer, topic string, partition int, t time.Time) (int64, error) {
+	conn, err := Dial(ctx, conf, dialer)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read partitions of topic %s: %w", topic, err)
+	}
+
+	for _, p := range partitions {
+		if p.ID != partition {
+			continue
+		}
+
+		leader, err := dialLeader(ctx, dialer, p)
+		if err != nil {
+			return 0, err
+		}
+		defer leader.Close()
+
+		offset, err := leader.ReadOffset(t)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read offset of %s/%d at %s: %w", topic, partition, t, err)
+		}
+		if offset < 0 {
+			return leader.ReadLastOffset()
+		}
+		return offset, nil
+	}
+
+	return 0, fmt.Errorf("partition %s/%d not found", topic, partition)
+}
+
+func dialLeader(ctx context.Context, dialer *kafka.Dialer, partition kafka.Partition) (*kafka.Conn, error) {
+	conn, err := dialer.DialPartition(ctx, "tcp", "", partition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to leader of %s/%d: %w", partition.Topic, partition.ID, err)
+	}
+
+	return conn, nil
+}
+
+// GroupStatus is the partition assignment and progress of a consumer group.
+type GroupStatus struct {
+	GroupID    string           `json:"group_id"`
+	State      string           `json:"state"`
+	Members    []GroupMember    `json:"members"`
+	Partitions []GroupPartition `json:"partitions"`
+}
+
+// GroupMember is a consumer of the group with its assigned partitions by topic.
+type GroupMember struct {
+	MemberID    string           `json:"member_id"`
+	ClientID    string           `json:"client_id"`
+	ClientHost  string           `json:"client_host"`
+	Assignments map[string][]int `json:"assignments"`
+}
+
+// GroupPartition is the committed offset of the group on a partition, Committed is -1
+// when the group has not committed yet and Lag is then counted from First.
+type GroupPartition struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Committed int64  `json:"committed"`
+	First     int64  `json:"first"`
+	Last      int64  `json:"last"`
+	Lag       int64  `json:"lag"`
+}
+
+// ReadGroupStatus describes the consumer group members and its committed offsets on the topics.
+func ReadGroupStatus(ctx context.Context, conf config.KafkaConfig, dialer *kafka.Dialer, transport *kafka.Transport, topics []string) (*GroupStatus, error) {
+	client := &kafka.Client{Addr: kafka.TCP(Brokers(conf)...), Timeout: dialTimeout, Transport: transport}
+	groupID := conf.ConsumerGroupName
+
+	groups, err := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{groupID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe group %s: %w", groupID, err)
+	}
+	if len(groups.Groups) != 1 {
+		return nil, fmt.Errorf("group %s not found", groupID)
+	}
+	group := groups.Groups[0]
+	if group.Error != nil {
+		return nil, fmt.Errorf("failed to describe group %s: %w", groupID, group.Error)
+	}
+
+	status := &GroupStatus{GroupID: groupID, State: group.GroupState, Members: []GroupMember{}, Partitions: []GroupPartition{}}
+	for _, member := range group.Members {
+		assignments := map[string][]int{}
+		for _, topic := range member.MemberAssignments.Topics {
+			assignments[topic.Topic] = topic.Partitions
+		}
+		status.Members = append(status.Members, GroupMember{
+			MemberID:    member.MemberID,
+			ClientID:    member.ClientID,
+			ClientHost:  member.ClientHost,
+			Assignments: assignments,
+		})
+	}
+
+	request := &kafka.OffsetFetchRequest{GroupID: groupID, Topics: map[string][]int{}}
+	ranges := map[string][]PartitionOffsets{}
+	for _, topic := range topics {
+		offsets, err := ReadPartitionOffsets(ctx, conf, dialer, topic)
+		if err != nil {
+			return nil, err
+		}
+		ranges[topic] = offsets
+		for _, offset := range offsets {
+			request.Topics[topic] = append(request.Topics[topic], offset.Partition)
+		}
+	}
+
+	committed, err := client.OffsetFetch(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch offsets of group %s: %w", groupID, err)
+	}
+	if committed.Error != nil {
+		return nil, fmt.Errorf("failed to fetch offsets of group %s: %w", groupID, committed.Error)
+	}
+
+	for _, topic := range topics {
+		commits := map[int]int64{}
+		for _, partition := range committed.Topics[topic] {
+			commits[partition.Partition] = partition.CommittedOffset
+		}
+
+		for _, offset := range ranges[topic] {
+			partition := GroupPartition{
+				Topic:     topic,
+				Partition: offset.Partition,
+				Committed: -1,
+				First:     offset.First,
+				Last:      offset.Last,
+				Lag:       offset.Last - offset.First,
+			}
+			if c, ok := commits[offset.Partition]; ok && c >= 0 {
+				partition.Committed = c
+				partition.Lag = offset.Last - c
+			}
+			status.Partitions = append(status.Partitions, partition)
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,30 @@
+package kafkaclient
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Consumer is the part of the reader used by the worker, implemented by *kafka.Reader,
+// *RegexReader and *MemoryReader.
+type Consumer interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Producer is the part of the writer used for the success and error topics,
+// implemented by *kafka.Writer and *MemoryWriter.
+type Producer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+var (
+	_ Consumer = (*kafka.Reader)(nil)
+	_ Consumer = (*RegexReader)(nil)
+	_ Consumer = (*MemoryReader)(nil)
+	_ Producer = (*kafka.Writer)(nil)
+	_ Producer = (*MemoryWriter)(nil)
+)
@@ -0,0 +1,186 @@
+package kafkaclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+const dialTimeout = 10 * time.Second
+
+// Brokers returns the broker addresses in host:port format.
+// The Hosts list takes precedence over the single Host and Port.
+func Brokers(conf config.KafkaConfig) []string {
+	if len(conf.Broker.Hosts) > 0 {
+		return conf.Broker.Hosts
+	}
+
+	return []string{
+		fmt.Sprintf("%s:%s", conf.Broker.Host, conf.Broker.Port),
+	}
+}
+
+// NewReaderConfig builds the consumer group reader config from the consumer tuning settings.
+// The config is expected to be validated, unknown values fall back to the kafka-go defaults.
+func NewReaderConfig(conf config.KafkaConfig, dialer *kafka.Dialer) kafka.ReaderConfig {
+	readerConfig := kafka.ReaderConfig{
+		Brokers:           Brokers(conf),
+		Topic:             conf.Topic,
+		GroupTopics:       conf.Topics,
+		GroupID:           conf.ConsumerGroupName,
+		Dialer:            dialer,
+		MinBytes:          conf.Consumer.MinBytes,
+		MaxBytes:          conf.Consumer.MaxBytes,
+		MaxWait:           conf.Consumer.MaxWait,
+		SessionTimeout:    conf.Consumer.SessionTimeout,
+		HeartbeatInterval: conf.Consumer.HeartbeatInterval,
+		CommitInterval:    conf.Consumer.CommitInterval,
+		StartOffset:       kafka.FirstOffset,
+	}
+
+	if readerConfig.MaxBytes == 0 {
+		readerConfig.MaxBytes = config.DefaultConsumerMaxBytes
+	}
+
+	if conf.Consumer.StartOffset == "latest" {
+		readerConfig.StartOffset = kafka.LastOffset
+	}
+
+	if conf.Consumer.RebalanceStrategy == "roundrobin" {
+		readerConfig.GroupBalancers = []kafka.GroupBalancer{kafka.RoundRobinGroupBalancer{}}
+	}
+
+	if conf.Consumer.IsolationLevel == "read_committed" {
+		readerConfig.IsolationLevel = kafka.ReadCommitted
+	}
+
+	return readerConfig
+}
+
+// NewDialer creates the dialer used by readers and admin connections,
+// configured with the same SASL and TLS settings as NewTransport.
+func NewDialer(conf config.KafkaConfig) (*kafka.Dialer, error) {
+	mechanism, tlsConfig, err := newSecurity(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafka.Dialer{
+		Timeout:       dialTimeout,
+		DualStack:     true,
+		SASLMechanism: mechanism,
+		TLS:           tlsConfig,
+	}, nil
+}
+
+// NewTransport creates the transport used by writers,
+// configured with the same SASL and TLS settings as NewDialer.
+func NewTransport(conf config.KafkaConfig) (*kafka.Transport, error) {
+	mechanism, tlsConfig, err := newSecurity(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafka.Transport{
+		DialTimeout: dialTimeout,
+		SASL:        mechanism,
+		TLS:         tlsConfig,
+	}, nil
+}
+
+// NewWriter creates a writer to the given topic using a shared transport.
+// Messages are partitioned by key with the same hashing as the Java client.
+func NewWriter(conf config.KafkaConfig, topic string, transport *kafka.Transport) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(Brokers(conf)...),
+		Topic:        topic,
+		Balancer:     &kafka.Murmur2Balancer{},
+		RequiredAcks: kafka.RequireAll,
+		Transport:    transport,
+	}
+}
+
+func newSecurity(conf config.KafkaConfig) (sasl.Mechanism, *tls.Config, error) {
+	mechanism, err := newSASLMechanism(conf.SASL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(conf.TLS)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mechanism, tlsConfig, nil
+}
+
+func newSASLMechanism(conf config.KafkaSASLConfig) (sasl.Mechanism, error) {
+	if conf.Mechanism == nil {
+		return nil, nil
+	}
+
+	switch strings.ToUpper(*conf.Mechanism) {
+	case "PLAIN":
+		return plain.Mechanism{
+			Username: conf.Username,
+			Password: conf.Password,
+		}, nil
+	case "SCRAM-SHA-256":
+		return scram.Mechanism(scram.SHA256, conf.Username, conf.Password)
+	case "SCRAM-SHA-512":
+		return scram.Mechanism(scram.SHA512, conf.Username, conf.Password)
+	default:
+		return nil, fmt.Errorf("invalid SASL mechanism: %s. Allowed mechanisms: PLAIN, SCRAM-SHA-256, SCRAM-SHA-512", *conf.Mechanism)
+	}
+}
+
+func newTLSConfig(conf config.KafkaTLSConfig) (*tls.Config, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: conf.InsecureSkipVerify,
+	}
+
+	if conf.ServerName != nil {
+		tlsConfig.ServerName = *conf.ServerName
+	}
+
+	if conf.CAFile != nil {
+		ca, err := os.ReadFile(*conf.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kafka CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no valid certificate found in kafka CA file %s", *conf.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (conf.CertFile == nil) != (conf.KeyFile == nil) {
+		return nil, fmt.Errorf("kafka TLS client certificate requires both cert file and key file")
+	}
+
+	if conf.CertFile != nil {
+		cert, err := tls.LoadX509KeyPair(*conf.CertFile, *conf.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kafka client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
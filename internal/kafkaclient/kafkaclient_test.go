@@ -0,0 +1,158 @@
+package kafkaclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+func TestNewSASLMechanism(t *testing.T) {
+	tests := []struct {
+		name      string
+		mechanism *string
+		wantName  string
+		wantErr   bool
+	}{
+		{
+			name:      "no mechanism",
+			mechanism: nil,
+		},
+		{
+			name:      "plain",
+			mechanism: stringPtr("plain"),
+			wantName:  "PLAIN",
+		},
+		{
+			name:      "scram sha 256",
+			mechanism: stringPtr("SCRAM-SHA-256"),
+			wantName:  "SCRAM-SHA-256",
+		},
+		{
+			name:      "scram sha 512",
+			mechanism: stringPtr("SCRAM-SHA-512"),
+			wantName:  "SCRAM-SHA-512",
+		},
+		{
+			name:      "unsupported",
+			mechanism: stringPtr("GSSAPI"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newSASLMechanism(config.KafkaSASLConfig{
+				Mechanism: tt.mechanism,
+				Username:  "user",
+				Password:  "pass",
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newSASLMechanism() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantName == "" {
+				if got != nil && !tt.wantErr {
+					t.Errorf("newSASLMechanism() got = %v, want nil", got)
+				}
+				return
+			}
+
+			if got.Name() != tt.wantName {
+				t.Errorf("newSASLMechanism() name = %q, want %q", got.Name(), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.KafkaTLSConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{
+			name:    "disabled",
+			conf:    config.KafkaTLSConfig{},
+			wantNil: true,
+		},
+		{
+			name: "enabled with system roots",
+			conf: config.KafkaTLSConfig{Enabled: true, ServerName: stringPtr("kafka.internal")},
+		},
+		{
+			name:    "missing CA file",
+			conf:    config.KafkaTLSConfig{Enabled: true, CAFile: stringPtr("/not/exists.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "cert without key",
+			conf:    config.KafkaTLSConfig{Enabled: true, CertFile: stringPtr("/tmp/cert.pem")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTLSConfig(tt.conf)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && (got == nil) != tt.wantNil {
+				t.Errorf("newTLSConfig() got = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestNewReaderConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		consumer     config.KafkaConsumerConfig
+		wantMaxBytes int
+	}{
+		{
+			name:         "defaults",
+			wantMaxBytes: config.DefaultConsumerMaxBytes,
+		},
+		{
+			name:         "only min bytes",
+			consumer:     config.KafkaConsumerConfig{MinBytes: 10},
+			wantMaxBytes: config.DefaultConsumerMaxBytes,
+		},
+		{
+			name:         "min and max bytes",
+			consumer:     config.KafkaConsumerConfig{MinBytes: 10, MaxBytes: 2048, MaxWait: time.Second},
+			wantMaxBytes: 2048,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewReaderConfig(config.KafkaConfig{
+				Broker:            config.KafkaBrokerConfig{Host: "kafka", Port: "9092"},
+				Topic:             "orders",
+				ConsumerGroupName: "sink",
+				Consumer:          tt.consumer,
+			}, nil)
+
+			if got.MaxBytes != tt.wantMaxBytes {
+				t.Errorf("NewReaderConfig() MaxBytes = %d, want %d", got.MaxBytes, tt.wantMaxBytes)
+			}
+			// kafka.NewReader panics when the config does not validate
+			if err := got.Validate(); err != nil {
+				t.Errorf("NewReaderConfig() is invalid: %v", err)
+			}
+		})
+	}
+}
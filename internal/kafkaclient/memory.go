@@ -0,0 +1,174 @@
+package kafkaclient
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// MemoryBroker is an in-memory broker with a single partition per topic,
+// used to test the pipeline end to end without a Kafka cluster.
+type MemoryBroker struct {
+	mu        sync.Mutex
+	topics    map[string][]kafka.Message
+	committed map[string]map[string]int64
+	changed   chan struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics:    map[string][]kafka.Message{},
+		committed: map[string]map[string]int64{},
+		changed:   make(chan struct{}),
+	}
+}
+
+// Produce appends the messages to the topic and assigns their offsets.
+func (b *MemoryBroker) Produce(topic string, msgs ...kafka.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, msg := range msgs {
+		msg.Topic = topic
+		msg.Partition = 0
+		msg.Offset = int64(len(b.topics[topic]))
+		if msg.Time.IsZero() {
+			msg.Time = time.Now()
+		}
+		b.topics[topic] = append(b.topics[topic], msg)
+	}
+	b.notify()
+}
+
+// Messages returns a copy of every message written to the topic.
+func (b *MemoryBroker) Messages(topic string) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]kafka.Message{}, b.topics[topic]...)
+}
+
+// Committed returns the next offset the group will consume from the topic, 0 when nothing is committed.
+func (b *MemoryBroker) Committed(group, topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.committed[group][topic]
+}
+
+// WaitCommitted blocks until the group committed up to offset or ctx is done.
+func (b *MemoryBroker) WaitCommitted(ctx context.Context, group, topic string, offset int64) error {
+	for {
+		b.mu.Lock()
+		done := b.committed[group][topic] >= offset
+		changed := b.changed
+		b.mu.Unlock()
+		if done {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// notify wakes up every waiting reader, it must be called with mu held.
+func (b *MemoryBroker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// Reader consumes the topics with the consumer group, starting from the committed offsets.
+func (b *MemoryBroker) Reader(group string, topics ...string) *MemoryReader {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	positions := make(map[string]int64, len(topics))
+	for _, topic := range topics {
+		positions[topic] = b.committed[group][topic]
+	}
+
+	return &MemoryReader{broker: b, group: group, topics: topics, positions: positions, closed: make(chan struct{})}
+}
+
+// Writer produces to the topic.
+func (b *MemoryBroker) Writer(topic string) *MemoryWriter {
+	return &MemoryWriter{broker: b, topic: topic}
+}
+
+// MemoryReader is a Consumer reading from a MemoryBroker.
+type MemoryReader struct {
+	broker    *MemoryBroker
+	group     string
+	topics    []string
+	positions map[string]int64
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (r *MemoryReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		r.broker.mu.Lock()
+		for _, topic := range r.topics {
+			position := r.positions[topic]
+			if position < int64(len(r.broker.topics[topic])) {
+				msg := r.broker.topics[topic][position]
+				r.positions[topic] = position + 1
+				r.broker.mu.Unlock()
+				return msg, nil
+			}
+		}
+		changed := r.broker.changed
+		r.broker.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-r.closed:
+			return kafka.Message{}, io.EOF
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		}
+	}
+}
+
+func (r *MemoryReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+
+	if r.broker.committed[r.group] == nil {
+		r.broker.committed[r.group] = map[string]int64{}
+	}
+	for _, msg := range msgs {
+		if msg.Offset+1 > r.broker.committed[r.group][msg.Topic] {
+			r.broker.committed[r.group][msg.Topic] = msg.Offset + 1
+		}
+	}
+	r.broker.notify()
+	return nil
+}
+
+func (r *MemoryReader) Close() error {
+	r.closeOnce.Do(func() { close(r.closed) })
+	return nil
+}
+
+// MemoryWriter is a Producer writing to a MemoryBroker topic.
+type MemoryWriter struct {
+	broker *MemoryBroker
+	topic  string
+}
+
+func (w *MemoryWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.broker.Produce(w.topic, msgs...)
+	return nil
+}
+
+func (w *MemoryWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,200 @@
+package kafkaclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"go.uber.org/zap"
+)
+
+// RegexReader consumes every topic matching a regex with a single consumer group.
+// The topic list is refreshed periodically from the cluster metadata, and the
+// underlying reader is recreated with the new topic list when it changes.
+type RegexReader struct {
+	conf     config.KafkaConfig
+	dialer   *kafka.Dialer
+	regex    *regexp.Regexp
+	interval time.Duration
+	logr     *zap.Logger
+
+	mu     sync.RWMutex
+	reader *kafka.Reader
+	topics []string
+	closed bool
+	done   chan struct{}
+}
+
+// NewRegexReader resolves the topics matching conf.TopicRegex and starts the periodic refresh.
+// It fails when no topic matches, since a consumer group needs at least one topic to join.
+func NewRegexReader(ctx context.Context, conf config.KafkaConfig, dialer *kafka.Dialer, logr *zap.Logger) (*RegexReader, error) {
+	regex, err := regexp.Compile(*conf.TopicRegex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic regex: %w", err)
+	}
+
+	r := &RegexReader{
+		conf:     conf,
+		dialer:   dialer,
+		regex:    regex,
+		interval: conf.TopicRefreshInterval,
+		logr:     logr,
+		done:     make(chan struct{}),
+	}
+
+	topics, err := r.matchTopics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("no topic matches regex %q", *conf.TopicRegex)
+	}
+
+	r.subscribe(topics)
+	go r.refresh()
+
+	return r, nil
+}
+
+// Topics returns the currently subscribed topics.
+func (r *RegexReader) Topics() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.topics
+}
+
+// FetchMessage reads the next message from any of the subscribed topics without committing it.
+// When the topic list changes during the read, the read continues on the new reader.
+func (r *RegexReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		r.mu.RLock()
+		reader, closed := r.reader, r.closed
+		r.mu.RUnlock()
+
+		if closed {
+			return kafka.Message{}, io.EOF
+		}
+
+		msg, err := reader.FetchMessage(ctx)
+		if errors.Is(err, io.EOF) && ctx.Err() == nil && r.current() != reader {
+			continue
+		}
+		return msg, err
+	}
+}
+
+// CommitMessages commits the messages on the current reader. Messages fetched
+// before the topic list changed can not be committed and are redelivered.
+func (r *RegexReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	return r.current().CommitMessages(ctx, msgs...)
+}
+
+// Close stops the topic refresh and closes the underlying reader.
+func (r *RegexReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+
+	return r.reader.Close()
+}
+
+func (r *RegexReader) current() *kafka.Reader {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.reader
+}
+
+func (r *RegexReader) refresh() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		topics, err := r.matchTopics(ctx)
+		cancel()
+		if err != nil {
+			r.logr.Warn("failed to refresh topics matching regex", zap.Error(err))
+			continue
+		}
+
+		if len(topics) == 0 || strings.Join(topics, ",") == strings.Join(r.Topics(), ",") {
+			continue
+		}
+
+		r.logr.Info("topics matching regex changed, resubscribing", zap.Strings("topics", topics))
+		r.subscribe(topics)
+	}
+}
+
+// subscribe swaps the underlying reader with a new one for the given topics.
+func (r *RegexReader) subscribe(topics []string) {
+	readerConfig := NewReaderConfig(r.conf, r.dialer)
+	readerConfig.Topic = ""
+	readerConfig.GroupTopics = topics
+	reader := kafka.NewReader(readerConfig)
+
+	r.mu.Lock()
+	old := r.reader
+	if r.closed {
+		r.mu.Unlock()
+		reader.Close()
+		return
+	}
+	r.reader, r.topics = reader, topics
+	r.mu.Unlock()
+
+	if old != nil {
+		if err := old.Close(); err != nil {
+			r.logr.Warn("failed to close previous reader", zap.Error(err))
+		}
+	}
+}
+
+// matchTopics lists the non-internal topics in the cluster matching the regex, sorted by name.
+func (r *RegexReader) matchTopics(ctx context.Context) ([]string, error) {
+	conn, err := Dial(ctx, r.conf, r.dialer)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read topic metadata: %w", err)
+	}
+
+	seen := map[string]bool{}
+	topics := []string{}
+	for _, partition := range partitions {
+		topic := partition.Topic
+		if seen[topic] || strings.HasPrefix(topic, "__") || !r.regex.MatchString(topic) {
+			continue
+		}
+		seen[topic] = true
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	return topics, nil
+}
@@ -0,0 +1,188 @@
+// Package metrics is a small registry of labeled counters and gauges exposed
+// in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Default is the registry used by the worker and exposed on METRICS_ADDR.
+var Default = NewRegistry()
+
+type kind string
+
+const (
+	kindCounter kind = "counter"
+	kindGauge   kind = "gauge"
+)
+
+// Registry holds the metrics by name.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]*Metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: map[string]*Metric{}}
+}
+
+// Counter returns the counter with the name, creating it on first use.
+func (r *Registry) Counter(name, help string, labels ...string) *Metric {
+	return r.metric(kindCounter, name, help, labels)
+}
+
+// Gauge returns the gauge with the name, creating it on first use.
+func (r *Registry) Gauge(name, help string, labels ...string) *Metric {
+	return r.metric(kindGauge, name, help, labels)
+}
+
+func (r *Registry) metric(k kind, name, help string, labels []string) *Metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m, ok := r.metrics[name]; ok {
+		if m.kind != k || len(m.labels) != len(labels) {
+			panic(fmt.Sprintf("metric %s registered twice with different kind or labels", name))
+		}
+		return m
+	}
+
+	m := &Metric{kind: k, name: name, help: help, labels: labels, values: map[string]*sample{}}
+	r.metrics[name] = m
+	return m
+}
+
+// WriteText writes every metric in the Prometheus text exposition format, sorted by name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		r.mu.Lock()
+		m := r.metrics[name]
+		r.mu.Unlock()
+		if err := m.writeText(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the metrics for scraping.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.WriteText(w)
+}
+
+// Metric is a counter or gauge with a value per label combination.
+type Metric struct {
+	kind   kind
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*sample
+}
+
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+// Inc adds 1 to the value of the label values.
+func (m *Metric) Inc(labelValues ...string) {
+	m.Add(1, labelValues...)
+}
+
+// Add adds delta to the value of the label values, counters only go up.
+func (m *Metric) Add(delta float64, labelValues ...string) {
+	if m.kind == kindCounter && delta < 0 {
+		panic(fmt.Sprintf("counter %s cannot decrease", m.name))
+	}
+	m.update(labelValues, func(s *sample) { s.value += delta })
+}
+
+// Set sets the gauge value of the label values.
+func (m *Metric) Set(value float64, labelValues ...string) {
+	if m.kind != kindGauge {
+		panic(fmt.Sprintf("cannot set counter %s", m.name))
+	}
+	m.update(labelValues, func(s *sample) { s.value = value })
+}
+
+// Value returns the current value of the label values, 0 when never updated.
+func (m *Metric) Value(labelValues ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.values[strings.Join(labelValues, "\xff")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (m *Metric) update(labelValues []string, fn func(s *sample)) {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", m.name, len(m.labels), len(labelValues)))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := m.values[key]
+	if !ok {
+		s = &sample{labelValues: append([]string{}, labelValues...)}
+		m.values[key] = s
+	}
+	fn(s)
+}
+
+func (m *Metric) writeText(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(m.values))
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := m.values[key]
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", m.name, m.formatLabels(s.labelValues), strconv.FormatFloat(s.value, 'g', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Metric) formatLabels(values []string) string {
+	if len(m.labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(m.labels))
+	for i, label := range m.labels {
+		pairs[i] = label + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	registry := NewRegistry()
+	messages := registry.Counter("sink_messages_total", "Messages by result.", "pipeline", "result")
+	up := registry.Gauge("sink_up", "Whether the sink is up.")
+
+	messages.Inc("orders", "success")
+	messages.Add(2, "orders", "success")
+	messages.Inc(`pay"ments`, "error")
+	up.Set(1)
+
+	var b strings.Builder
+	if err := registry.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP sink_messages_total Messages by result.
+# TYPE sink_messages_total counter
+sink_messages_total{pipeline="orders",result="success"} 3
+sink_messages_total{pipeline="pay\"ments",result="error"} 1
+# HELP sink_up Whether the sink is up.
+# TYPE sink_up gauge
+sink_up 1
+`
+	if b.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	if got := messages.Value("orders", "success"); got != 3 {
+		t.Errorf("Value() = %v, want 3", got)
+	}
+	if registry.Counter("sink_messages_total", "", "pipeline", "result") != messages {
+		t.Error("Counter() should return the registered metric")
+	}
+}
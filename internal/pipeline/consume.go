@@ -0,0 +1,78 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+	"go.uber.org/zap"
+)
+
+// ErrInterrupted means the in-flight message was cancelled by the shutdown timeout,
+// its offset is not committed so it is redelivered on restart.
+var ErrInterrupted = errors.New("message interrupted by shutdown")
+
+// Processor handles a single consumed message.
+type Processor interface {
+	Process(ctx context.Context, msg kafka.Message) error
+}
+
+// Consume processes messages until ctx is cancelled, the offset is committed only
+// when processing completed so an interrupted message is redelivered on restart.
+// procCtx is used for processing and committing, so the in-flight message can
+// finish after ctx is cancelled. It returns nil on a clean shutdown.
+func Consume(ctx, procCtx context.Context, reader kafkaclient.Consumer, proc Processor, logr *zap.Logger) error {
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logr.Error(
+				"failed to read message",
+				zap.Any("message", msg),
+				zap.Error(err),
+			)
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("reader closed: %w", err)
+			}
+			continue
+		}
+		logr.Debug(
+			"processing message",
+			zap.String("topic", msg.Topic),
+			zap.String("payload", string(msg.Value)),
+			zap.Int("offset", int(msg.Offset)),
+		)
+
+		if err := proc.Process(procCtx, msg); err != nil {
+			if procCtx.Err() != nil {
+				logr.Warn(
+					"message interrupted by shutdown, it will be redelivered",
+					zap.String("topic", msg.Topic),
+					zap.Int("partition", msg.Partition),
+					zap.Int64("offset", msg.Offset),
+				)
+				return ErrInterrupted
+			}
+			logr.Error(
+				"failed to process message",
+				zap.Any("message", msg),
+				zap.Error(err),
+			)
+		}
+
+		if err := reader.CommitMessages(procCtx, msg); err != nil {
+			logr.Error(
+				"failed to commit message",
+				zap.String("topic", msg.Topic),
+				zap.Int("partition", msg.Partition),
+				zap.Int64("offset", msg.Offset),
+				zap.Error(err),
+			)
+		}
+	}
+}
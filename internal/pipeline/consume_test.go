@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+	"github.com/urbanindo/go-kafka-http-sink/internal/processor"
+	"go.uber.org/zap"
+)
+
+func TestConsumeEndToEnd(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, string(body))
+		mu.Unlock()
+		if r.Header.Get("kafka_key") == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"rejected"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	broker := kafkaclient.NewMemoryBroker()
+	broker.Produce("orders",
+		kafka.Message{Key: []byte("good"), Value: []byte(`{"id":1}`)},
+		kafka.Message{Key: []byte("bad"), Value: []byte(`{"id":2}`)},
+		kafka.Message{Key: []byte("undecodable"), Value: []byte("\x00\x00\x00\x00\x00not-json")},
+	)
+
+	conf := &config.Config{HttpApiUrl: server.URL, Sink: config.SinkConfig{Type: processor.SinkTypeHTTP}}
+	proc := processor.NewProcessor("orders", conf, zap.NewNop(), broker.Writer("orders-error"), broker.Writer("orders-success"))
+	reader := broker.Reader("sink", "orders")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Run(ctx, context.Background(), "orders-sink", reader, proc, zap.NewNop())
+	}()
+
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelWait()
+	if err := broker.WaitCommitted(waitCtx, "sink", "orders", 3); err != nil {
+		t.Fatalf("messages were not committed: %v", err)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Consume() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0] != `{"id":1}` || received[1] != `{"id":2}` {
+		t.Errorf("API received %v, want the two decodable messages", received)
+	}
+
+	if success, failed := messagesTotal.Value("orders-sink", "orders", resultSuccess), messagesTotal.Value("orders-sink", "orders", resultError); success != 1 || failed != 2 {
+		t.Errorf("messages metric = %v success, %v error, want 1 and 2", success, failed)
+	}
+
+	successes := broker.Messages("orders-success")
+	if len(successes) != 1 || string(successes[0].Key) != "good" || string(successes[0].Value) != `{"ok":true}` {
+		t.Errorf("success topic = %v, want the response of the good message", successes)
+	}
+
+	errs := broker.Messages("orders-error")
+	if len(errs) != 2 {
+		t.Fatalf("error topic has %d messages, want 2", len(errs))
+	}
+	wantClasses := []string{processor.ErrorClassHTTP, processor.ErrorClassDecode}
+	for i, msg := range errs {
+		var payload processor.ErrorPayload
+		if err := json.Unmarshal(msg.Value, &payload); err != nil {
+			t.Fatalf("invalid error payload: %v", err)
+		}
+		if payload.ErrorClass != wantClasses[i] || payload.Offset != int64(i+1) {
+			t.Errorf("error payload %d = %s at offset %d, want %s at offset %d", i, payload.ErrorClass, payload.Offset, wantClasses[i], i+1)
+		}
+	}
+}
+
+func TestConsumeInterrupted(t *testing.T) {
+	broker := kafkaclient.NewMemoryBroker()
+	broker.Produce("orders", kafka.Message{Key: []byte("slow"), Value: []byte(`{}`)})
+
+	procCtx, cancelProc := context.WithCancel(context.Background())
+	proc := processorFunc(func(ctx context.Context, msg kafka.Message) error {
+		cancelProc()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := Consume(context.Background(), procCtx, broker.Reader("sink", "orders"), proc, zap.NewNop())
+	if err != ErrInterrupted {
+		t.Errorf("Consume() error = %v, want %v", err, ErrInterrupted)
+	}
+	if committed := broker.Committed("sink", "orders"); committed != 0 {
+		t.Errorf("committed offset = %d, the interrupted message should not be committed", committed)
+	}
+}
+
+type processorFunc func(ctx context.Context, msg kafka.Message) error
+
+func (f processorFunc) Process(ctx context.Context, msg kafka.Message) error {
+	return f(ctx, msg)
+}
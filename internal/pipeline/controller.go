@@ -0,0 +1,206 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+)
+
+// ErrNotPaused is returned when an operation requires the pipeline to be paused first.
+var ErrNotPaused = errors.New("pipeline is not paused")
+
+// idlePollInterval is how often Pause checks whether the in-flight message is committed.
+const idlePollInterval = 50 * time.Millisecond
+
+// Controller is a Consumer that can be paused, resumed and restarted at runtime,
+// e.g. from the admin API. While paused no message is handed to the processor,
+// the underlying reader keeps its group membership.
+type Controller struct {
+	newReader func() (kafkaclient.Consumer, error)
+
+	mu         sync.Mutex
+	reader     kafkaclient.Consumer
+	generation int
+	paused     bool
+	resumed    chan struct{}
+	inFlight   bool
+	closed     bool
+}
+
+// NewController creates the reader with newReader, which is also used to recreate it on Restart.
+func NewController(newReader func() (kafkaclient.Consumer, error)) (*Controller, error) {
+	reader, err := newReader()
+	if err != nil {
+		return nil, err
+	}
+
+	resumed := make(chan struct{})
+	close(resumed)
+	return &Controller{newReader: newReader, reader: reader, resumed: resumed}, nil
+}
+
+// Reader returns the current reader.
+func (c *Controller) Reader() kafkaclient.Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.reader
+}
+
+// Paused tells whether the pipeline is paused.
+func (c *Controller) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.paused
+}
+
+// Pause stops handing messages to the processor and waits until the in-flight message,
+// if any, is committed so no message is being delivered once it returns.
+func (c *Controller) Pause(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.paused {
+		c.paused = true
+		c.resumed = make(chan struct{})
+	}
+	c.mu.Unlock()
+
+	ticker := time.NewTicker(idlePollInterval)
+	defer ticker.Stop()
+	for {
+		c.mu.Lock()
+		idle := !c.inFlight
+		c.mu.Unlock()
+		if idle {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Resume continues consuming after Pause.
+func (c *Controller) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		c.paused = false
+		close(c.resumed)
+	}
+}
+
+// Restart closes the reader and creates a new one, so the group rebalances and
+// every member continues from the committed offsets. The pipeline must be paused.
+func (c *Controller) Restart() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.paused {
+		return ErrNotPaused
+	}
+
+	if err := c.reader.Close(); err != nil {
+		return err
+	}
+	reader, err := c.newReader()
+	if err != nil {
+		return err
+	}
+	c.reader = reader
+	c.generation++
+	return nil
+}
+
+func (c *Controller) waitResumed(ctx context.Context) error {
+	c.mu.Lock()
+	resumed := c.resumed
+	c.mu.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Controller) current() (kafkaclient.Consumer, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.reader, c.generation
+}
+
+// FetchMessage waits while paused. A message fetched before a Restart is dropped,
+// since the new reader delivers it again from the committed offset.
+func (c *Controller) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		if err := c.waitResumed(ctx); err != nil {
+			return kafka.Message{}, err
+		}
+
+		reader, generation := c.current()
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if _, current := c.current(); current != generation && ctx.Err() == nil {
+				continue
+			}
+			return msg, err
+		}
+
+		if err := c.waitResumed(ctx); err != nil {
+			return kafka.Message{}, err
+		}
+
+		c.mu.Lock()
+		if c.generation != generation {
+			c.mu.Unlock()
+			continue
+		}
+		c.inFlight = true
+		c.mu.Unlock()
+		return msg, nil
+	}
+}
+
+func (c *Controller) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	c.mu.Lock()
+	reader := c.reader
+	c.inFlight = false
+	c.mu.Unlock()
+
+	return reader.CommitMessages(ctx, msgs...)
+}
+
+// Commit commits the offsets through the current reader, e.g. to reset the group offsets.
+// The pipeline must be paused so the processor does not commit over them.
+func (c *Controller) Commit(ctx context.Context, msgs ...kafka.Message) error {
+	c.mu.Lock()
+	reader, paused := c.reader, c.paused
+	c.mu.Unlock()
+
+	if !paused {
+		return ErrNotPaused
+	}
+	return reader.CommitMessages(ctx, msgs...)
+}
+
+func (c *Controller) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.reader.Close()
+}
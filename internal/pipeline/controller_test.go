@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+)
+
+func TestControllerPauseResume(t *testing.T) {
+	broker := kafkaclient.NewMemoryBroker()
+	broker.Produce("orders", kafka.Message{Value: []byte("1")}, kafka.Message{Value: []byte("2")})
+	controller, err := NewController(func() (kafkaclient.Consumer, error) {
+		return broker.Reader("sink", "orders"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msg, err := controller.FetchMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pause waits for the in-flight message to be committed
+	paused := make(chan error)
+	go func() { paused <- controller.Pause(ctx) }()
+	select {
+	case <-paused:
+		t.Fatal("Pause() returned before the in-flight message was committed")
+	case <-time.After(2 * idlePollInterval):
+	}
+	if err := controller.CommitMessages(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-paused; err != nil {
+		t.Fatal(err)
+	}
+
+	fetchCtx, cancelFetch := context.WithTimeout(ctx, 2*idlePollInterval)
+	defer cancelFetch()
+	if _, err := controller.FetchMessage(fetchCtx); err == nil {
+		t.Fatal("FetchMessage() should wait while paused")
+	}
+
+	controller.Resume()
+	msg, err = controller.FetchMessage(ctx)
+	if err != nil || string(msg.Value) != "2" {
+		t.Errorf("FetchMessage() after resume = %q, %v, want 2", msg.Value, err)
+	}
+}
+
+func TestControllerRestart(t *testing.T) {
+	broker := kafkaclient.NewMemoryBroker()
+	broker.Produce("orders", kafka.Message{Value: []byte("1")}, kafka.Message{Value: []byte("2")}, kafka.Message{Value: []byte("3")})
+	controller, err := NewController(func() (kafkaclient.Consumer, error) {
+		return broker.Reader("sink", "orders"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := controller.Restart(); err != ErrNotPaused {
+		t.Errorf("Restart() error = %v, want %v", err, ErrNotPaused)
+	}
+
+	if err := controller.Pause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Reset the group to the last message
+	if err := controller.Commit(ctx, kafka.Message{Topic: "orders", Offset: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := controller.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	controller.Resume()
+
+	msg, err := controller.FetchMessage(ctx)
+	if err != nil || string(msg.Value) != "3" {
+		t.Errorf("FetchMessage() after restart = %q, %v, want 3", msg.Value, err)
+	}
+}
@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+	"github.com/urbanindo/go-kafka-http-sink/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Result label values of messagesTotal.
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+var (
+	messagesTotal = metrics.Default.Counter(
+		"kafka_http_sink_messages_total", "Messages processed by the pipeline by result.",
+		"pipeline", "topic", "result",
+	)
+	processingSeconds = metrics.Default.Counter(
+		"kafka_http_sink_processing_seconds_total", "Time spent processing messages, divide by the message count for the average.",
+		"pipeline", "topic",
+	)
+	pipelineUp = metrics.Default.Gauge(
+		"kafka_http_sink_pipeline_up", "Whether the pipeline is consuming.",
+		"pipeline",
+	)
+)
+
+// Run consumes with the pipeline name as metrics label, see Consume.
+func Run(ctx, procCtx context.Context, name string, reader kafkaclient.Consumer, proc Processor, logr *zap.Logger) error {
+	pipelineUp.Set(1, name)
+	defer pipelineUp.Set(0, name)
+
+	return Consume(ctx, procCtx, reader, Instrument(name, proc), logr)
+}
+
+// Instrument counts the processed messages and the processing time with the pipeline label.
+func Instrument(name string, proc Processor) Processor {
+	return &instrumentedProcessor{name: name, proc: proc}
+}
+
+type instrumentedProcessor struct {
+	name string
+	proc Processor
+}
+
+func (p *instrumentedProcessor) Process(ctx context.Context, msg kafka.Message) error {
+	start := time.Now()
+	err := p.proc.Process(ctx, msg)
+	processingSeconds.Add(time.Since(start).Seconds(), p.name, msg.Topic)
+
+	result := resultSuccess
+	if err != nil {
+		result = resultError
+	}
+	messagesTotal.Inc(p.name, msg.Topic, result)
+	return err
+}
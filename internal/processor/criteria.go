@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+// statusRange is an inclusive range of HTTP status codes.
+type statusRange struct {
+	min, max int
+}
+
+// successCriteria decides whether an HTTP response is successful, see config.HttpSuccessConfig.
+// A nil successCriteria accepts every 2xx response.
+type successCriteria struct {
+	statusCodes []statusRange
+	body        []config.BodyPredicate
+	headers     []string
+}
+
+func newSuccessCriteria(conf config.HttpSuccessConfig) (*successCriteria, error) {
+	c := &successCriteria{headers: conf.Headers}
+
+	for _, spec := range conf.StatusCodes {
+		min, max, err := config.ParseStatusRange(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid success status code: %w", err)
+		}
+		c.statusCodes = append(c.statusCodes, statusRange{min: min, max: max})
+	}
+	if len(c.statusCodes) == 0 {
+		c.statusCodes = []statusRange{{min: 200, max: 299}}
+	}
+
+	for _, spec := range conf.Body {
+		predicate, err := config.ParseBodyPredicate(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid success body predicate: %w", err)
+		}
+		c.body = append(c.body, predicate)
+	}
+
+	return c, nil
+}
+
+// statusOK tells whether the status code is one of the successful status codes.
+func (c *successCriteria) statusOK(code int) bool {
+	if c == nil {
+		return code >= 200 && code < 300
+	}
+	for _, r := range c.statusCodes {
+		if code >= r.min && code <= r.max {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns why a response with a successful status code is not successful, nil when it is.
+func (c *successCriteria) check(headers map[string][]string, body []byte) error {
+	if c == nil {
+		return nil
+	}
+	for _, name := range c.headers {
+		if len(lookupHeader(headers, name)) == 0 {
+			return fmt.Errorf("response header %s is missing", name)
+		}
+	}
+
+	if len(c.body) == 0 {
+		return nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("response is not valid JSON: %w", err)
+	}
+
+	for _, predicate := range c.body {
+		field, found := lookupField(doc, predicate.Path)
+		path := strings.Join(predicate.Path, ".")
+		switch predicate.Op {
+		case config.PredicateExists:
+			if !found {
+				return fmt.Errorf("response field %s is missing", path)
+			}
+		case config.PredicateEqual:
+			if !found || !reflect.DeepEqual(field, predicate.Value) {
+				return fmt.Errorf("response field %s is not %v", path, predicate.Value)
+			}
+		case config.PredicateNotEqual:
+			if found && reflect.DeepEqual(field, predicate.Value) {
+				return fmt.Errorf("response field %s is %v", path, predicate.Value)
+			}
+		}
+	}
+	return nil
+}
+
+// lookupField returns the field at the path of the decoded JSON document, null is not found.
+func lookupField(doc interface{}, path []string) (interface{}, bool) {
+	for _, name := range path {
+		object, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if doc, ok = object[name]; !ok {
+			return nil, false
+		}
+	}
+	return doc, doc != nil
+}
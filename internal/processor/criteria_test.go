@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+func TestSuccessCriteria(t *testing.T) {
+	tests := []struct {
+		name       string
+		conf       config.HttpSuccessConfig
+		statusCode int
+		headers    http.Header
+		body       string
+		wantStatus bool
+		wantErr    bool
+	}{
+		{
+			name:       "default status codes",
+			statusCode: http.StatusNoContent,
+			wantStatus: true,
+		},
+		{
+			name:       "default rejects redirect",
+			statusCode: http.StatusFound,
+		},
+		{
+			name:       "configured status codes",
+			conf:       config.HttpSuccessConfig{StatusCodes: []string{"200", "404-404"}},
+			statusCode: http.StatusNotFound,
+			wantStatus: true,
+		},
+		{
+			name:       "status code not configured",
+			conf:       config.HttpSuccessConfig{StatusCodes: []string{"200", "404-404"}},
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "body predicates match",
+			conf:       config.HttpSuccessConfig{Body: []string{"success == true", "data.id", "status != failed"}},
+			statusCode: http.StatusOK,
+			body:       `{"success": true, "data": {"id": 1}, "status": "done"}`,
+			wantStatus: true,
+		},
+		{
+			name:       "200 with success false",
+			conf:       config.HttpSuccessConfig{Body: []string{"success == true"}},
+			statusCode: http.StatusOK,
+			body:       `{"success": false}`,
+			wantStatus: true,
+			wantErr:    true,
+		},
+		{
+			name:       "not equal predicate",
+			conf:       config.HttpSuccessConfig{Body: []string{"status != failed"}},
+			statusCode: http.StatusOK,
+			body:       `{"status": "failed"}`,
+			wantStatus: true,
+			wantErr:    true,
+		},
+		{
+			name:       "missing field",
+			conf:       config.HttpSuccessConfig{Body: []string{"data.id"}},
+			statusCode: http.StatusOK,
+			body:       `{"data": null}`,
+			wantStatus: true,
+			wantErr:    true,
+		},
+		{
+			name:       "body is not JSON",
+			conf:       config.HttpSuccessConfig{Body: []string{"success == true"}},
+			statusCode: http.StatusOK,
+			body:       "OK",
+			wantStatus: true,
+			wantErr:    true,
+		},
+		{
+			name:       "required header present",
+			conf:       config.HttpSuccessConfig{Headers: []string{"x-request-id"}},
+			statusCode: http.StatusOK,
+			headers:    http.Header{"X-Request-Id": {"req-1"}},
+			wantStatus: true,
+		},
+		{
+			name:       "required header missing",
+			conf:       config.HttpSuccessConfig{Headers: []string{"X-Request-Id"}},
+			statusCode: http.StatusOK,
+			wantStatus: true,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			criteria, err := newSuccessCriteria(tt.conf)
+			if err != nil {
+				t.Fatalf("newSuccessCriteria() error = %v", err)
+			}
+
+			if got := criteria.statusOK(tt.statusCode); got != tt.wantStatus {
+				t.Errorf("statusOK(%d) = %v, want %v", tt.statusCode, got, tt.wantStatus)
+			}
+			if err := criteria.check(tt.headers, []byte(tt.body)); (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/dedup"
+	"github.com/urbanindo/go-kafka-http-sink/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Dedup key sources supported by DEDUP_SOURCE.
+const (
+	DedupSourceKey    = "key"
+	DedupSourceHeader = "header"
+	DedupSourceField  = "field"
+)
+
+var duplicatesTotal = metrics.Default.Counter(
+	"kafka_http_sink_duplicates_total", "Duplicate messages dropped by the dedup window.",
+	"pipeline", "topic",
+)
+
+// deduplicator drops the messages whose key was delivered within the window.
+// A nil deduplicator lets every message through.
+type deduplicator struct {
+	source   string
+	header   string
+	field    []string
+	pipeline string
+	store    dedup.Store
+	logr     *zap.Logger
+}
+
+// newDeduplicator returns nil when DEDUP_SOURCE is not set.
+func newDeduplicator(pipeline string, conf *config.Config, logr *zap.Logger) *deduplicator {
+	if conf.Dedup.Source == "" {
+		return nil
+	}
+
+	d := &deduplicator{
+		source:   conf.Dedup.Source,
+		header:   conf.Dedup.Header,
+		pipeline: pipeline,
+		store:    dedup.NewMemoryStore(conf.Dedup.Size, conf.Dedup.Window),
+		logr:     logr,
+	}
+	if conf.Dedup.Field != "" {
+		d.field = strings.Split(conf.Dedup.Field, ".")
+	}
+	return d
+}
+
+// key returns the dedup key of the message scoped by its topic, value is the decoded value.
+func (d *deduplicator) key(msg kafka.Message, value []byte) (string, error) {
+	var key string
+	switch d.source {
+	case DedupSourceHeader:
+		for _, header := range msg.Headers {
+			if header.Key == d.header {
+				key = string(header.Value)
+				break
+			}
+		}
+	case DedupSourceField:
+		field, err := jsonField(value, d.field)
+		if err != nil {
+			return "", fmt.Errorf("dedup key: %w", err)
+		}
+		key = field
+	default:
+		key = string(msg.Key)
+	}
+
+	if key == "" {
+		return "", fmt.Errorf("dedup %s is empty", d.source)
+	}
+	return msg.Topic + "\x00" + key, nil
+}
+
+// seen returns the key of the message and whether it was delivered within the window.
+// A message without key or a store failure lets the message through.
+func (d *deduplicator) seen(ctx context.Context, msg kafka.Message, value []byte) (string, bool) {
+	if d == nil {
+		return "", false
+	}
+
+	key, err := d.key(msg, value)
+	if err != nil {
+		d.logr.Warn("message has no dedup key, delivering it", zap.String("topic", msg.Topic), zap.Int64("offset", msg.Offset), zap.Error(err))
+		return "", false
+	}
+
+	seen, err := d.store.Contains(ctx, key)
+	if err != nil {
+		d.logr.Warn("failed to check dedup store, delivering the message", zap.String("topic", msg.Topic), zap.Int64("offset", msg.Offset), zap.Error(err))
+		return key, false
+	}
+	if seen {
+		duplicatesTotal.Inc(d.pipeline, msg.Topic)
+		d.logr.Info(
+			"dropped duplicate message",
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+		)
+	}
+	return key, seen
+}
+
+// delivered remembers the key of the delivered message.
+func (d *deduplicator) delivered(ctx context.Context, key string) {
+	if d == nil || key == "" {
+		return
+	}
+
+	if err := d.store.Add(ctx, key); err != nil {
+		d.logr.Warn("failed to add delivered message to dedup store", zap.Error(err))
+	}
+}
+
+func (d *deduplicator) close() error {
+	if d == nil {
+		return nil
+	}
+	return d.store.Close()
+}
@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"go.uber.org/zap"
+)
+
+func TestProcessDedup(t *testing.T) {
+	conf := &config.Config{
+		Dedup: config.DedupConfig{Source: DedupSourceField, Field: "event.id", Window: time.Minute, Size: 10},
+	}
+
+	var out failingBuffer
+	processor := &messageProcessor{
+		sink:  &writerSink{out: &out, name: "test"},
+		logr:  zap.NewNop(),
+		dedup: newDeduplicator("dedup-test", conf, zap.NewNop()),
+	}
+
+	// messages are processed in order, the window keeps what the previous ones delivered
+	tests := []struct {
+		name          string
+		topic         string
+		value         string
+		failWrite     bool
+		wantDelivered bool
+		wantErr       bool
+	}{
+		{name: "first delivery", topic: "orders", value: `{"event":{"id":"e1"}}`, wantDelivered: true},
+		{name: "duplicate is dropped", topic: "orders", value: `{"event":{"id":"e1"},"retry":true}`},
+		{name: "same key on another topic", topic: "payments", value: `{"event":{"id":"e1"}}`, wantDelivered: true},
+		{name: "missing key is delivered", topic: "orders", value: `{"event":{}}`, wantDelivered: true},
+		{name: "failed delivery", topic: "orders", value: `{"event":{"id":"e2"}}`, failWrite: true, wantErr: true},
+		{name: "failed key is not remembered", topic: "orders", value: `{"event":{"id":"e2"}}`, wantDelivered: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			out.fail = tt.failWrite
+			before := duplicatesTotal.Value("dedup-test", tt.topic)
+
+			err := processor.Process(context.Background(), kafka.Message{Topic: tt.topic, Value: []byte(tt.value)})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			delivered := strings.TrimSpace(out.String()) != ""
+			if delivered != tt.wantDelivered {
+				t.Errorf("delivered = %v, want %v", delivered, tt.wantDelivered)
+			}
+
+			dropped := duplicatesTotal.Value("dedup-test", tt.topic) - before
+			if wantDropped := !tt.wantDelivered && !tt.wantErr; (dropped == 1) != wantDropped {
+				t.Errorf("duplicates metric increased by %v, want dropped %v", dropped, wantDropped)
+			}
+		})
+	}
+}
+
+// failingBuffer fails every write while fail is set.
+type failingBuffer struct {
+	bytes.Buffer
+	fail bool
+}
+
+func (b *failingBuffer) Write(p []byte) (int, error) {
+	if b.fail {
+		return 0, errors.New("write failed")
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,208 @@
+package processor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+// Body encodings supported by HTTP_BODY_ENCODING.
+const (
+	BodyEncodingJSON   = "json"
+	BodyEncodingForm   = "form"
+	BodyEncodingNDJSON = "ndjson"
+	BodyEncodingRaw    = "raw"
+	BodyEncodingAvro   = "avro"
+)
+
+// Body compressions supported by HTTP_BODY_COMPRESSION.
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// bodyEncoder encodes the request body and compresses it when configured.
+// A nil bodyEncoder sends the decoded value as is, without Content-Type.
+type bodyEncoder struct {
+	encoding    string
+	contentType string
+	compression string
+	zstd        *zstd.Encoder
+}
+
+// newBodyEncoder returns nil when no HTTP_BODY setting is set, the decoded value is then
+// sent as is like before the encodings were added.
+func newBodyEncoder(conf config.HttpBodyConfig) (*bodyEncoder, error) {
+	if conf == (config.HttpBodyConfig{}) {
+		return nil, nil
+	}
+
+	e := &bodyEncoder{encoding: conf.Encoding, contentType: conf.ContentType, compression: conf.Compression}
+	switch e.encoding {
+	case "":
+		e.encoding = BodyEncodingJSON
+	case BodyEncodingJSON, BodyEncodingForm, BodyEncodingNDJSON, BodyEncodingRaw, BodyEncodingAvro:
+	default:
+		return nil, fmt.Errorf("invalid body encoding: %s. Allowed encodings: json, form, ndjson, raw, avro", e.encoding)
+	}
+
+	switch e.compression {
+	case "", CompressionGzip:
+	case CompressionZstd:
+		encoder, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+		e.zstd = encoder
+	default:
+		return nil, fmt.Errorf("invalid body compression: %s. Allowed compressions: gzip, zstd", e.compression)
+	}
+
+	return e, nil
+}
+
+// encode returns the body and its content type, value is the decoded value.
+func (e *bodyEncoder) encode(msg kafka.Message, value []byte) ([]byte, string, error) {
+	if e == nil {
+		return value, "", nil
+	}
+
+	body, contentType, err := e.encodeBody(msg, value)
+	if err != nil {
+		return nil, "", err
+	}
+	if e.contentType != "" {
+		contentType = e.contentType
+	}
+	return body, contentType, nil
+}
+
+func (e *bodyEncoder) encodeBody(msg kafka.Message, value []byte) ([]byte, string, error) {
+	switch e.encoding {
+	case BodyEncodingForm:
+		form, err := flattenForm(value)
+		if err != nil {
+			return nil, "", err
+		}
+		return []byte(form.Encode()), "application/x-www-form-urlencoded", nil
+	case BodyEncodingNDJSON:
+		body, err := ndjsonLines(value)
+		if err != nil {
+			return nil, "", err
+		}
+		return body, "application/x-ndjson", nil
+	case BodyEncodingRaw:
+		if json.Valid(msg.Value) {
+			return msg.Value, "application/json", nil
+		}
+		return msg.Value, "application/octet-stream", nil
+	case BodyEncodingAvro:
+		// the schema registry wire format is a magic byte and a 4 byte schema ID before the Avro binary
+		if len(msg.Value) < 5 || msg.Value[0] != 0 {
+			return nil, "", fmt.Errorf("message value is not in schema registry wire format")
+		}
+		return msg.Value[5:], "avro/binary", nil
+	default:
+		return value, "application/json", nil
+	}
+}
+
+// contentEncoding returns the Content-Encoding header value, empty without compression.
+func (e *bodyEncoder) contentEncoding() string {
+	if e == nil {
+		return ""
+	}
+	return e.compression
+}
+
+// compress compresses the encoded body when configured.
+func (e *bodyEncoder) compress(body []byte) ([]byte, error) {
+	switch e.contentEncoding() {
+	case CompressionGzip:
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		if _, err := w.Write(body); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	case CompressionZstd:
+		return e.zstd.EncodeAll(body, nil), nil
+	default:
+		return body, nil
+	}
+}
+
+// close releases the zstd encoder goroutines.
+func (e *bodyEncoder) close() {
+	if e != nil && e.zstd != nil {
+		e.zstd.Close()
+	}
+}
+
+// flattenForm flattens the JSON object into form fields, nested objects and arrays use
+// brackets, e.g. {"a":{"b":1},"c":[2]} is a[b]=1&c[0]=2. Null values are empty.
+func flattenForm(value []byte) (url.Values, error) {
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("form encoding requires a JSON object: %w", err)
+	}
+
+	object, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("form encoding requires a JSON object")
+	}
+
+	form := url.Values{}
+	for key, field := range object {
+		flattenField(form, key, field)
+	}
+	return form, nil
+}
+
+func flattenField(form url.Values, key string, field interface{}) {
+	switch v := field.(type) {
+	case map[string]interface{}:
+		for name, child := range v {
+			flattenField(form, key+"["+name+"]", child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flattenField(form, key+"["+strconv.Itoa(i)+"]", child)
+		}
+	case nil:
+		form.Add(key, "")
+	case string:
+		form.Add(key, v)
+	default:
+		form.Add(key, fmt.Sprint(v))
+	}
+}
+
+// ndjsonLines writes every element of a JSON array as a compact JSON line, any other value as a single line.
+func ndjsonLines(value []byte) ([]byte, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(value, &elements); err != nil {
+		elements = []json.RawMessage{value}
+	}
+
+	var b bytes.Buffer
+	for _, element := range elements {
+		if err := json.Compact(&b, element); err != nil {
+			return nil, fmt.Errorf("ndjson encoding requires a JSON value: %w", err)
+		}
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,168 @@
+package processor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+func TestBodyEncoder(t *testing.T) {
+	tests := []struct {
+		name            string
+		conf            config.HttpBodyConfig
+		msgValue        string
+		value           string
+		wantBody        string
+		wantContentType string
+		wantErr         bool
+	}{
+		{
+			name:     "unset",
+			value:    `{"id":1}`,
+			wantBody: `{"id":1}`,
+		},
+		{
+			name:            "json",
+			conf:            config.HttpBodyConfig{Encoding: BodyEncodingJSON},
+			value:           `{"id":1}`,
+			wantBody:        `{"id":1}`,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "form",
+			conf:            config.HttpBodyConfig{Encoding: BodyEncodingForm},
+			value:           `{"name":"a b","user":{"id":10,"admin":false},"tags":["x","y"],"note":null,"amount":1.50}`,
+			wantBody:        "amount=1.50&name=a+b&note=&tags%5B0%5D=x&tags%5B1%5D=y&user%5Badmin%5D=false&user%5Bid%5D=10",
+			wantContentType: "application/x-www-form-urlencoded",
+		},
+		{
+			name:    "form requires object",
+			conf:    config.HttpBodyConfig{Encoding: BodyEncodingForm},
+			value:   `[1,2]`,
+			wantErr: true,
+		},
+		{
+			name:            "ndjson array",
+			conf:            config.HttpBodyConfig{Encoding: BodyEncodingNDJSON},
+			value:           `[{"id": 1}, {"id": 2}]`,
+			wantBody:        "{\"id\":1}\n{\"id\":2}\n",
+			wantContentType: "application/x-ndjson",
+		},
+		{
+			name:            "ndjson object",
+			conf:            config.HttpBodyConfig{Encoding: BodyEncodingNDJSON},
+			value:           `{"id": 1}`,
+			wantBody:        "{\"id\":1}\n",
+			wantContentType: "application/x-ndjson",
+		},
+		{
+			name:            "raw",
+			conf:            config.HttpBodyConfig{Encoding: BodyEncodingRaw},
+			msgValue:        "\x00\x00\x00\x00\x01raw",
+			value:           `{"decoded":true}`,
+			wantBody:        "\x00\x00\x00\x00\x01raw",
+			wantContentType: "application/octet-stream",
+		},
+		{
+			name:            "avro",
+			conf:            config.HttpBodyConfig{Encoding: BodyEncodingAvro, ContentType: "application/vnd.orders+avro"},
+			msgValue:        "\x00\x00\x00\x00\x07\x02ab",
+			value:           `{"id":"ab"}`,
+			wantBody:        "\x02ab",
+			wantContentType: "application/vnd.orders+avro",
+		},
+		{
+			name:     "avro without wire format",
+			conf:     config.HttpBodyConfig{Encoding: BodyEncodingAvro},
+			msgValue: `{"id":"ab"}`,
+			value:    `{"id":"ab"}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder, err := newBodyEncoder(tt.conf)
+			if err != nil {
+				t.Fatalf("newBodyEncoder() error = %v", err)
+			}
+
+			body, contentType, err := encoder.encode(kafka.Message{Value: []byte(tt.msgValue)}, []byte(tt.value))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("encode() body = %q, want %q", body, tt.wantBody)
+			}
+			if contentType != tt.wantContentType {
+				t.Errorf("encode() content type = %q, want %q", contentType, tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestBodyCompression(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"id":1}`), 100)
+
+	tests := []struct {
+		name       string
+		decompress func(compressed []byte) ([]byte, error)
+	}{
+		{
+			name: CompressionGzip,
+			decompress: func(compressed []byte) ([]byte, error) {
+				r, err := gzip.NewReader(bytes.NewReader(compressed))
+				if err != nil {
+					return nil, err
+				}
+				return io.ReadAll(r)
+			},
+		},
+		{
+			name: CompressionZstd,
+			decompress: func(compressed []byte) ([]byte, error) {
+				r, err := zstd.NewReader(nil)
+				if err != nil {
+					return nil, err
+				}
+				defer r.Close()
+				return r.DecodeAll(compressed, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder, err := newBodyEncoder(config.HttpBodyConfig{Compression: tt.name})
+			if err != nil {
+				t.Fatalf("newBodyEncoder() error = %v", err)
+			}
+			defer encoder.close()
+
+			if encoder.contentEncoding() != tt.name {
+				t.Errorf("contentEncoding() = %q, want %q", encoder.contentEncoding(), tt.name)
+			}
+
+			compressed, err := encoder.compress(body)
+			if err != nil {
+				t.Fatalf("compress() error = %v", err)
+			}
+			if len(compressed) >= len(body) {
+				t.Errorf("compressed body has %d bytes, want less than %d", len(compressed), len(body))
+			}
+
+			got, err := tt.decompress(compressed)
+			if err != nil {
+				t.Fatalf("decompress error = %v", err)
+			}
+			if !bytes.Equal(got, body) {
+				t.Errorf("decompressed body = %q, want %q", got, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package processor
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Endpoint selection strategies supported by HTTP_ENDPOINTS_STRATEGY.
+const (
+	StrategyRoundRobin = "round_robin"
+	StrategyKeyHash    = "key_hash"
+)
+
+var endpointEjectionsTotal = metrics.Default.Counter(
+	"kafka_http_sink_endpoint_ejections_total", "Endpoints ejected after consecutive failures.",
+	"pipeline", "endpoint",
+)
+
+// endpoint is one replica of the API with its passive health state.
+type endpoint struct {
+	base         *url.URL
+	failures     int
+	ejectedUntil time.Time
+}
+
+// endpointBalancer selects the endpoint of every request. A nil balancer keeps the URL as configured.
+type endpointBalancer struct {
+	mu         sync.Mutex
+	pipeline   string
+	endpoints  []*endpoint
+	strategy   string
+	ejectAfter int
+	ejectFor   time.Duration
+	next       int
+	now        func() time.Time
+	logr       *zap.Logger
+}
+
+// newEndpointBalancer returns nil when HTTP_ENDPOINTS_URLS is not set.
+func newEndpointBalancer(pipeline string, conf config.HttpEndpointsConfig, logr *zap.Logger) (*endpointBalancer, error) {
+	if len(conf.URLs) == 0 {
+		return nil, nil
+	}
+
+	strategy := conf.Strategy
+	switch strategy {
+	case "":
+		strategy = StrategyRoundRobin
+	case StrategyRoundRobin, StrategyKeyHash:
+	default:
+		return nil, fmt.Errorf("invalid endpoint strategy: %s. Allowed strategies: round_robin, key_hash", conf.Strategy)
+	}
+
+	b := &endpointBalancer{
+		pipeline:   pipeline,
+		strategy:   strategy,
+		ejectAfter: conf.EjectAfter,
+		ejectFor:   conf.EjectFor,
+		now:        time.Now,
+		logr:       logr,
+	}
+	for _, raw := range conf.URLs {
+		base, err := url.Parse(raw)
+		if err != nil || base.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint URL %q", raw)
+		}
+		b.endpoints = append(b.endpoints, &endpoint{base: base})
+	}
+
+	return b, nil
+}
+
+// order returns the endpoints in the order they are tried for the message, the selected one first.
+// Ejected endpoints are only tried after the healthy ones, so every endpoint gets one attempt.
+func (b *endpointBalancer) order(msg kafka.Message) []*endpoint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	endpoints := b.ordered(msg)
+	if b.strategy != StrategyKeyHash {
+		b.next = (b.next + 1) % len(b.endpoints)
+	}
+	return endpoints
+}
+
+// peek returns the endpoint the message would be sent to without taking a turn of the rotation.
+func (b *endpointBalancer) peek(msg kafka.Message) *endpoint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.ordered(msg)[0]
+}
+
+// ordered is order without advancing the rotation, b.mu must be held.
+func (b *endpointBalancer) ordered(msg kafka.Message) []*endpoint {
+	start := b.next
+	if b.strategy == StrategyKeyHash {
+		h := fnv.New32a()
+		h.Write(msg.Key)
+		start = int(h.Sum32() % uint32(len(b.endpoints)))
+	}
+
+	now := b.now()
+	var healthy, ejected []*endpoint
+	for i := range b.endpoints {
+		e := b.endpoints[(start+i)%len(b.endpoints)]
+		if now.Before(e.ejectedUntil) {
+			ejected = append(ejected, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+
+	return append(healthy, ejected...)
+}
+
+// record records the outcome of the request, the endpoint is ejected after
+// EjectAfter consecutive failures and is healthy again after any success.
+func (b *endpointBalancer) record(e *endpoint, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		e.failures = 0
+		return
+	}
+
+	e.failures++
+	if b.ejectAfter > 0 && e.failures >= b.ejectAfter {
+		e.failures = 0
+		e.ejectedUntil = b.now().Add(b.ejectFor)
+		endpointEjectionsTotal.Inc(b.pipeline, e.base.Host)
+		b.logr.Warn("ejected failing endpoint", zap.String("endpoint", e.base.Host), zap.Duration("for", b.ejectFor))
+	}
+}
+
+// EndpointState is the passive health of an endpoint of HTTP_ENDPOINTS_URLS. An ejected
+// endpoint is only tried after the healthy ones until EjectedUntil.
+type EndpointState struct {
+	URL          string     `json:"url"`
+	Failures     int        `json:"consecutive_failures"`
+	EjectedUntil *time.Time `json:"ejected_until,omitempty"`
+}
+
+// states returns the state of every endpoint, nil for a nil balancer.
+func (b *endpointBalancer) states() []EndpointState {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	states := make([]EndpointState, 0, len(b.endpoints))
+	for _, e := range b.endpoints {
+		state := EndpointState{URL: e.base.String(), Failures: e.failures}
+		if now.Before(e.ejectedUntil) {
+			until := e.ejectedUntil
+			state.EjectedUntil = &until
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// resolve replaces the scheme and host of the rendered URL with the endpoint ones.
+func (e *endpoint) resolve(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	u.Scheme = e.base.Scheme
+	u.Host = e.base.Host
+	u.User = e.base.User
+	return u.String(), nil
+}
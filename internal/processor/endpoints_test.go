@@ -0,0 +1,182 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"go.uber.org/zap"
+)
+
+func TestEndpointBalancer(t *testing.T) {
+	urls := []string{"http://api-1:8080", "http://api-2:8080", "http://api-3:8080"}
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		conf     config.HttpEndpointsConfig
+		setup    func(b *endpointBalancer)
+		keys     []string
+		wantHost []string
+	}{
+		{
+			name:     "round robin by default",
+			conf:     config.HttpEndpointsConfig{URLs: urls},
+			keys:     []string{"a", "a", "a", "a"},
+			wantHost: []string{"api-1:8080", "api-2:8080", "api-3:8080", "api-1:8080"},
+		},
+		{
+			name:     "key hash is sticky",
+			conf:     config.HttpEndpointsConfig{URLs: urls, Strategy: StrategyKeyHash},
+			keys:     []string{"order-1", "order-3", "order-5", "order-1", "order-3"},
+			wantHost: []string{"api-2:8080", "api-1:8080", "api-3:8080", "api-2:8080", "api-1:8080"},
+		},
+		{
+			name: "ejected endpoint is tried last",
+			conf: config.HttpEndpointsConfig{URLs: urls, EjectAfter: 2, EjectFor: time.Minute},
+			setup: func(b *endpointBalancer) {
+				for i := 0; i < 2; i++ {
+					b.record(b.endpoints[1], true)
+				}
+			},
+			keys:     []string{"a", "a", "a"},
+			wantHost: []string{"api-1:8080", "api-3:8080", "api-3:8080"},
+		},
+		{
+			name: "success resets the failures",
+			conf: config.HttpEndpointsConfig{URLs: urls, EjectAfter: 2, EjectFor: time.Minute},
+			setup: func(b *endpointBalancer) {
+				for _, failed := range []bool{true, false, true} {
+					b.record(b.endpoints[1], failed)
+				}
+			},
+			keys:     []string{"a", "a", "a"},
+			wantHost: []string{"api-1:8080", "api-2:8080", "api-3:8080"},
+		},
+		{
+			name: "ejection disabled",
+			conf: config.HttpEndpointsConfig{URLs: urls},
+			setup: func(b *endpointBalancer) {
+				for i := 0; i < 10; i++ {
+					b.record(b.endpoints[1], true)
+				}
+			},
+			keys:     []string{"a", "a"},
+			wantHost: []string{"api-1:8080", "api-2:8080"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := newEndpointBalancer("orders", tt.conf, zap.NewNop())
+			if err != nil {
+				t.Fatalf("newEndpointBalancer() error = %v", err)
+			}
+			b.now = func() time.Time { return now }
+			if tt.setup != nil {
+				tt.setup(b)
+			}
+
+			var hosts []string
+			for _, key := range tt.keys {
+				order := b.order(kafka.Message{Key: []byte(key)})
+				if len(order) != len(urls) {
+					t.Fatalf("order() returned %d endpoints, want %d", len(order), len(urls))
+				}
+				hosts = append(hosts, order[0].base.Host)
+			}
+			if !reflect.DeepEqual(hosts, tt.wantHost) {
+				t.Errorf("selected = %v, want %v", hosts, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestEndpointEjectionExpires(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	b, err := newEndpointBalancer("orders", config.HttpEndpointsConfig{
+		URLs:       []string{"http://api-1:8080", "http://api-2:8080"},
+		Strategy:   StrategyKeyHash,
+		EjectAfter: 1,
+		EjectFor:   time.Minute,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("newEndpointBalancer() error = %v", err)
+	}
+	b.now = func() time.Time { return now }
+
+	msg := kafka.Message{Key: []byte("order-1")}
+	sticky := b.order(msg)[0]
+	b.record(sticky, true)
+
+	if got := b.order(msg)[0]; got == sticky {
+		t.Errorf("order() selected the ejected endpoint %s", got.base.Host)
+	}
+	wantUntil := now.Add(time.Minute)
+	for _, state := range b.states() {
+		ejected := state.URL == sticky.base.String()
+		if got := state.EjectedUntil != nil && state.EjectedUntil.Equal(wantUntil); got != ejected {
+			t.Errorf("states() %s ejected until %v, want ejected = %v", state.URL, state.EjectedUntil, ejected)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if got := b.order(msg)[0]; got != sticky {
+		t.Errorf("order() selected %s, want %s back after the ejection", got.base.Host, sticky.base.Host)
+	}
+	for _, state := range b.states() {
+		if state.EjectedUntil != nil {
+			t.Errorf("states() %s still ejected after the ejection", state.URL)
+		}
+	}
+}
+
+func TestEndpointPeek(t *testing.T) {
+	b, err := newEndpointBalancer("orders", config.HttpEndpointsConfig{
+		URLs: []string{"http://api-1:8080", "http://api-2:8080"},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("newEndpointBalancer() error = %v", err)
+	}
+
+	msg := kafka.Message{Key: []byte("order-1")}
+	for i := 0; i < 2; i++ {
+		if got := b.peek(msg); got != b.endpoints[0] {
+			t.Errorf("peek() selected %s, want api-1:8080 until a message is sent", got.base.Host)
+		}
+	}
+	if got := b.order(msg)[0]; got != b.endpoints[0] {
+		t.Errorf("order() selected %s, want api-1:8080 after peeking", got.base.Host)
+	}
+	if got := b.peek(msg); got != b.endpoints[1] {
+		t.Errorf("peek() selected %s, want api-2:8080 after a message is sent", got.base.Host)
+	}
+}
+
+func TestEndpointResolve(t *testing.T) {
+	b, err := newEndpointBalancer("orders", config.HttpEndpointsConfig{URLs: []string{"https://api-1:8443"}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("newEndpointBalancer() error = %v", err)
+	}
+
+	tests := []struct {
+		rawURL string
+		want   string
+	}{
+		{rawURL: "http://api:8080/v1/orders?source=kafka", want: "https://api-1:8443/v1/orders?source=kafka"},
+		{rawURL: "/v1/orders/42", want: "https://api-1:8443/v1/orders/42"},
+	}
+
+	for _, tt := range tests {
+		got, err := b.endpoints[0].resolve(tt.rawURL)
+		if err != nil {
+			t.Errorf("resolve(%q) error = %v", tt.rawURL, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolve(%q) = %s, want %s", tt.rawURL, got, tt.want)
+		}
+	}
+}
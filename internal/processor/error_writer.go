@@ -0,0 +1,155 @@
+package processor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
+)
+
+// Error classes tell at which stage a message failed.
+const (
+	// ErrorClassDecode means the message value could not be decoded.
+	ErrorClassDecode = "decode"
+	// ErrorClassRequest means the request could not be built, e.g. invalid URL or header template.
+	ErrorClassRequest = "request"
+	// ErrorClassTransport means the call failed without response, e.g. timeout or connection refused.
+	ErrorClassTransport = "transport"
+	// ErrorClassHTTP means the endpoint responded with a non-success status code.
+	ErrorClassHTTP = "http"
+	// ErrorClassResponse means the status code is successful but the response does not
+	// match the other success criteria, e.g. a 200 with {"success": false}.
+	ErrorClassResponse = "response"
+	// ErrorClassGRPC means the gRPC server responded with an error status, the code is in ResponseCode.
+	ErrorClassGRPC = "grpc"
+)
+
+// ErrorPayload is the value written to the error topic with the full failure context.
+// The original value is kept in OriginalValue when it is valid JSON, otherwise base64 encoded in OriginalValueBase64.
+type ErrorPayload struct {
+	Topic               string          `json:"topic"`
+	Partition           int             `json:"partition"`
+	Offset              int64           `json:"offset"`
+	Timestamp           time.Time       `json:"timestamp"`
+	Headers             []ErrorHeader   `json:"headers,omitempty"`
+	OriginalValue       json.RawMessage `json:"original_value,omitempty"`
+	OriginalValueBase64 string          `json:"original_value_base64,omitempty"`
+	ErrorClass          string          `json:"error_class"`
+	ErrorMessage        string          `json:"error_message"`
+	Attempts            int             `json:"attempts"`
+	URL                 string          `json:"url,omitempty"`
+	ResponseBody        string          `json:"response_body"`
+	ResponseCode        int             `json:"response_code"`
+	RequestBodyJSON     json.RawMessage `json:"request_body_json"`
+}
+
+// ErrorHeader is an original Kafka header, the value is base64 encoded in JSON since it may be binary.
+type ErrorHeader struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// newErrorPayload creates the error payload with the message coordinates and original value.
+func newErrorPayload(msg kafka.Message, class string, cause error) *ErrorPayload {
+	payload := &ErrorPayload{
+		Topic:        msg.Topic,
+		Partition:    msg.Partition,
+		Offset:       msg.Offset,
+		Timestamp:    msg.Time,
+		ErrorClass:   class,
+		ErrorMessage: cause.Error(),
+	}
+
+	for _, header := range msg.Headers {
+		payload.Headers = append(payload.Headers, ErrorHeader{
+			Key:   header.Key,
+			Value: header.Value,
+		})
+	}
+
+	if json.Valid(msg.Value) {
+		payload.OriginalValue = msg.Value
+	} else {
+		payload.OriginalValueBase64 = base64.StdEncoding.EncodeToString(msg.Value)
+	}
+
+	return payload
+}
+
+// setRequestBody sets the decoded request body, it is left empty when not valid JSON
+// since the original value is already kept in the payload.
+func (e *ErrorPayload) setRequestBody(value []byte) {
+	if json.Valid(value) {
+		e.RequestBodyJSON = value
+	}
+}
+
+// OriginalMessage reconstructs the consumed message from the error payload so it can be replayed.
+// Records written before the original value was kept fall back to the decoded request body.
+func (e *ErrorPayload) OriginalMessage(key []byte) (kafka.Message, error) {
+	msg := kafka.Message{
+		Topic:     e.Topic,
+		Partition: e.Partition,
+		Offset:    e.Offset,
+		Time:      e.Timestamp,
+		Key:       key,
+	}
+
+	for _, header := range e.Headers {
+		msg.Headers = append(msg.Headers, kafka.Header{
+			Key:   header.Key,
+			Value: header.Value,
+		})
+	}
+
+	switch {
+	case len(e.OriginalValue) > 0:
+		msg.Value = e.OriginalValue
+	case e.OriginalValueBase64 != "":
+		value, err := base64.StdEncoding.DecodeString(e.OriginalValueBase64)
+		if err != nil {
+			return kafka.Message{}, errors.Wrap(err, "OriginalMessage: invalid base64 original value")
+		}
+		msg.Value = value
+	case len(e.RequestBodyJSON) > 0 && string(e.RequestBodyJSON) != "null":
+		msg.Value = e.RequestBodyJSON
+	default:
+		return kafka.Message{}, errors.New("OriginalMessage: error payload has no original value")
+	}
+
+	return msg, nil
+}
+
+type ErrorWriter interface {
+	WriteError(ctx context.Context, key []byte, errPayload *ErrorPayload) error
+}
+
+func NewErrorWriter(kafkaWriter kafkaclient.Producer) ErrorWriter {
+	return &errorWriter{
+		writer: kafkaWriter,
+	}
+}
+
+type errorWriter struct {
+	writer kafkaclient.Producer
+}
+
+func (e *errorWriter) WriteError(ctx context.Context, key []byte, errPayload *ErrorPayload) error {
+	value, err := json.Marshal(errPayload)
+	if err != nil {
+		return errors.Wrap(err, "WriteError: failed to marshal payload")
+	}
+
+	if err := e.writer.WriteMessages(ctx, kafka.Message{
+		Key:   key,
+		Value: value,
+	}); err != nil {
+		return errors.Wrap(err, "WriteError: failed write to kafka")
+	}
+
+	return nil
+}
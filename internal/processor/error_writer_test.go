@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestErrorPayloadOriginalMessage(t *testing.T) {
+	written := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	headers := []kafka.Header{{Key: "trace-id", Value: []byte{0x00, 0x01}}}
+
+	tests := []struct {
+		name      string
+		msg       kafka.Message
+		payload   string
+		wantValue string
+		wantErr   bool
+	}{
+		{
+			name:      "json value round trip",
+			msg:       kafka.Message{Topic: "orders", Partition: 1, Offset: 7, Time: written, Headers: headers, Value: []byte(`{"id":1}`)},
+			wantValue: `{"id":1}`,
+		},
+		{
+			name:      "binary value round trip",
+			msg:       kafka.Message{Topic: "orders", Partition: 1, Offset: 7, Time: written, Headers: headers, Value: []byte{0x00, 0x00, 0x00, 0x00, 0x01, 0xff}},
+			wantValue: "\x00\x00\x00\x00\x01\xff",
+		},
+		{
+			name:      "request body fallback",
+			payload:   `{"topic":"orders","offset":7,"request_body_json":{"id":2}}`,
+			wantValue: `{"id":2}`,
+		},
+		{
+			name:    "invalid base64",
+			payload: `{"topic":"orders","original_value_base64":"%%%"}`,
+			wantErr: true,
+		},
+		{
+			name:    "no original value",
+			payload: `{"topic":"orders","request_body_json":null}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the payload goes through JSON like the records of the error topic
+			raw := []byte(tt.payload)
+			if tt.payload == "" {
+				var err error
+				raw, err = json.Marshal(newErrorPayload(tt.msg, ErrorClassHTTP, errors.New("bad gateway")))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			var payload ErrorPayload
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := payload.OriginalMessage([]byte("order-1"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OriginalMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if string(got.Value) != tt.wantValue || string(got.Key) != "order-1" || got.Topic != "orders" || got.Offset != 7 {
+				t.Errorf("OriginalMessage() = %+v", got)
+			}
+			if tt.payload == "" {
+				if got.Partition != tt.msg.Partition || !got.Time.Equal(tt.msg.Time) || !reflect.DeepEqual(got.Headers, tt.msg.Headers) {
+					t.Errorf("OriginalMessage() = %+v, want the coordinates and headers of %+v", got, tt.msg)
+				}
+			}
+		})
+	}
+}
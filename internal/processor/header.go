@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+const redactedValue = "[REDACTED]"
+
+// sensitiveHeaders lists header names whose values are always redacted in logs,
+// regardless of how the value was configured.
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"x-api-key":           true,
+	"x-auth-token":        true,
+}
+
+type httpHeader struct {
+	key    string
+	value  *template.Template
+	secret bool
+}
+
+// parseHeaderSpec parses a header spec in the form "Name: value".
+// The name ends at the first colon, so values may contain colons (URLs, timestamps, etc).
+// Surrounding whitespace of both name and value is trimmed.
+// The value is a text/template that can read secrets and message data:
+//
+//	Authorization: Bearer {{ file "/var/run/secrets/api/token" }}
+//	X-Api-Key: {{ env "API_KEY" }}
+//	X-Kafka-Topic: {{ .Topic }}
+//
+// Headers whose value comes from env or file are redacted in logs.
+func parseHeaderSpec(spec string, funcs template.FuncMap) (httpHeader, error) {
+	key, value, err := config.ParseHeaderSpec(spec)
+	if err != nil {
+		return httpHeader{}, fmt.Errorf("invalid header: %w", err)
+	}
+
+	tmpl, err := template.New(key).Funcs(funcs).Option("missingkey=zero").Parse(value)
+	if err != nil {
+		return httpHeader{}, fmt.Errorf("header %q has invalid value template: %w", key, err)
+	}
+
+	return httpHeader{
+		key:    key,
+		value:  tmpl,
+		secret: sensitiveHeaders[strings.ToLower(key)] || usesAnyFunc(tmpl.Tree.Root, "env", "file"),
+	}, nil
+}
+
+// render executes the header value template against the message context.
+// The rendered value must not contain line breaks to prevent header injection.
+func (h httpHeader) render(data templateData) (string, error) {
+	var b strings.Builder
+	if err := h.value.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render header %q: %w", h.key, err)
+	}
+
+	value := b.String()
+	if strings.ContainsAny(value, "\r\n") {
+		return "", fmt.Errorf("rendered header %q contains line break", h.key)
+	}
+
+	return value, nil
+}
+
+// String returns the header spec for logging with secret values redacted.
+func (h httpHeader) String() string {
+	if h.secret {
+		return h.key + ": " + redactedValue
+	}
+	return h.key + ": " + h.value.Root.String()
+}
+
+// isValidHeaderName checks the name against the RFC 7230 token grammar.
+func isValidHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			continue
+		}
+		if !strings.ContainsRune("!#$%&'*+-.^_`|~", r) {
+			return false
+		}
+	}
+	return true
+}
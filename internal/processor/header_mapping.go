@@ -0,0 +1,192 @@
+package processor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+// Binary header value policies supported by HTTP_KAFKA_HEADERS_BINARY.
+const (
+	BinaryHeaderBase64 = "base64"
+	BinaryHeaderDrop   = "drop"
+)
+
+// defaultHeaderMapping is the mapping of a sink created without config,
+// every Kafka header but id is sent and the key is sent as kafka_key.
+var defaultHeaderMapping = &headerMapping{
+	deny:      []string{"id"},
+	binary:    BinaryHeaderBase64,
+	keyHeader: "kafka_key",
+}
+
+// headerMapping selects, renames and encodes the Kafka headers sent as HTTP headers.
+type headerMapping struct {
+	allow     []string
+	deny      []string
+	rename    map[string]string
+	prefix    string
+	templates map[string]*template.Template
+	binary    string
+	keyHeader string
+}
+
+// headerTemplateData is the context of a Kafka header value template.
+// Example: "tenant: t-{{ .Value }}" or "trace: {{ .Topic }}/{{ .Value }}"
+type headerTemplateData struct {
+	templateData
+	Value string
+}
+
+// newHeaderMapping creates the mapping, a nil keyHeader or deny list is the default one.
+func newHeaderMapping(keyHeader *string, conf config.HttpKafkaHeadersConfig) (*headerMapping, error) {
+	m := &headerMapping{
+		allow:     conf.Allow,
+		deny:      defaultHeaderMapping.deny,
+		rename:    map[string]string{},
+		prefix:    conf.Prefix,
+		templates: map[string]*template.Template{},
+		binary:    conf.Binary,
+		keyHeader: defaultHeaderMapping.keyHeader,
+	}
+	if conf.Deny != nil {
+		m.deny = *conf.Deny
+	}
+	if keyHeader != nil {
+		m.keyHeader = *keyHeader
+	}
+	if m.binary == "" {
+		m.binary = BinaryHeaderBase64
+	}
+	if m.binary != BinaryHeaderBase64 && m.binary != BinaryHeaderDrop {
+		return nil, fmt.Errorf("invalid binary header policy: %s. Allowed values: base64, drop", m.binary)
+	}
+
+	for _, spec := range conf.Rename {
+		from, to, err := config.ParseHeaderRename(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header rename: %w", err)
+		}
+		m.rename[from] = to
+	}
+
+	for _, spec := range conf.Templates {
+		name, value, err := config.ParseHeaderTemplate(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header template: %w", err)
+		}
+		tmpl, err := template.New(name).Funcs(templateFuncs()).Option("missingkey=zero").Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("header %q has invalid value template: %w", name, err)
+		}
+		m.templates[name] = tmpl
+	}
+
+	return m, nil
+}
+
+// apply returns the key header and the mapped Kafka headers of the message.
+func (m *headerMapping) apply(msg kafka.Message) ([]headerValue, error) {
+	if m == nil {
+		m = defaultHeaderMapping
+	}
+
+	var headers []headerValue
+	if m.keyHeader != "" {
+		headers = append(headers, headerValue{key: m.keyHeader, value: sanitizeKey(msg.Key)})
+	}
+
+	var data *templateData
+	for _, header := range msg.Headers {
+		if !m.allowed(header.Key) {
+			continue
+		}
+
+		name, renamed := m.rename[header.Key]
+		if !renamed {
+			name = sanitizeHeaderName(m.prefix + header.Key)
+			if name == "" {
+				continue
+			}
+		}
+
+		value := string(header.Value)
+		if tmpl, ok := m.templates[header.Key]; ok {
+			if data == nil {
+				d := newTemplateData(msg)
+				data = &d
+			}
+			var b strings.Builder
+			if err := tmpl.Execute(&b, headerTemplateData{templateData: *data, Value: value}); err != nil {
+				return nil, fmt.Errorf("failed to render header %q: %w", header.Key, err)
+			}
+			value = b.String()
+		}
+
+		if !isPrintableHeaderValue(value) {
+			if m.binary == BinaryHeaderDrop {
+				continue
+			}
+			value = base64.StdEncoding.EncodeToString([]byte(value))
+		}
+
+		headers = append(headers, headerValue{key: name, value: value})
+	}
+
+	return headers, nil
+}
+
+// allowed tells whether the Kafka header is sent, deny takes precedence over allow.
+func (m *headerMapping) allowed(name string) bool {
+	if matchHeaderName(m.deny, name) {
+		return false
+	}
+	return len(m.allow) == 0 || matchHeaderName(m.allow, name)
+}
+
+// matchHeaderName matches the name against the patterns, a trailing * matches by prefix.
+func matchHeaderName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeHeaderName replaces the characters that are not allowed in an HTTP header name with "-".
+func sanitizeHeaderName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < utf8.RuneSelf && isValidHeaderName(string(r)) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// isPrintableHeaderValue tells whether the value can be sent as is, line breaks
+// and other control characters would break or inject headers.
+func isPrintableHeaderValue(value string) bool {
+	if !utf8.ValidString(value) {
+		return false
+	}
+	for _, r := range value {
+		if r != '\t' && !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
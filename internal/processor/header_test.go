@@ -0,0 +1,275 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+func TestParseHeaderSpec(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secretFile, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HEADER_TEST_API_KEY", "key-from-env")
+
+	tests := []struct {
+		name       string
+		spec       string
+		data       templateData
+		wantKey    string
+		wantValue  string
+		wantSecret bool
+		wantErr    bool
+	}{
+		{
+			name:      "literal value",
+			spec:      "X-Client:sink",
+			wantKey:   "X-Client",
+			wantValue: "sink",
+		},
+		{
+			name:      "value with colons and whitespace",
+			spec:      "  X-Callback :  http://host:8080/path  ",
+			wantKey:   "X-Callback",
+			wantValue: "http://host:8080/path",
+		},
+		{
+			name:       "value from file",
+			spec:       `Authorization: Bearer {{ file "` + secretFile + `" }}`,
+			wantKey:    "Authorization",
+			wantValue:  "Bearer s3cr3t",
+			wantSecret: true,
+		},
+		{
+			name:       "value from env",
+			spec:       `X-Token: {{ env "HEADER_TEST_API_KEY" }}`,
+			wantKey:    "X-Token",
+			wantValue:  "key-from-env",
+			wantSecret: true,
+		},
+		{
+			name:       "sensitive header name with literal value",
+			spec:       "X-Api-Key: abc",
+			wantKey:    "X-Api-Key",
+			wantValue:  "abc",
+			wantSecret: true,
+		},
+		{
+			name:      "templated from message",
+			spec:      "X-Source: {{ .Topic }}/{{ .Key }}",
+			data:      templateData{Topic: "orders", Key: "k1"},
+			wantKey:   "X-Source",
+			wantValue: "orders/k1",
+		},
+		{
+			name:    "missing separator",
+			spec:    "X-Client",
+			wantErr: true,
+		},
+		{
+			name:    "invalid header name",
+			spec:    "X Client: sink",
+			wantErr: true,
+		},
+		{
+			name:    "missing env",
+			spec:    `X-Token: {{ env "HEADER_TEST_NOT_SET" }}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			spec:    `X-Token: {{ file "/not/exists" }}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, err := parseHeaderSpec(tt.spec, templateFuncs())
+			var value string
+			if err == nil {
+				value, err = header.render(tt.data)
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseHeaderSpec() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if header.key != tt.wantKey {
+				t.Errorf("parseHeaderSpec() key = %q, want %q", header.key, tt.wantKey)
+			}
+			if value != tt.wantValue {
+				t.Errorf("render() value = %q, want %q", value, tt.wantValue)
+			}
+			if header.secret != tt.wantSecret {
+				t.Errorf("parseHeaderSpec() secret = %v, want %v", header.secret, tt.wantSecret)
+			}
+			if tt.wantSecret && header.String() != tt.wantKey+": "+redactedValue {
+				t.Errorf("String() = %q, should be redacted", header.String())
+			}
+		})
+	}
+}
+
+func TestTemplateFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(secretFile, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := parseHeaderSpec(`Authorization: Bearer {{ file "`+secretFile+`" }}`, templateFuncs())
+	if err != nil {
+		t.Fatalf("parseHeaderSpec() error = %v", err)
+	}
+
+	steps := []struct {
+		name   string
+		rotate func(t *testing.T)
+		want   string
+	}{
+		{
+			name: "first read",
+			want: "Bearer first",
+		},
+		{
+			name: "unchanged file is cached",
+			want: "Bearer first",
+		},
+		{
+			name: "rewritten file",
+			rotate: func(t *testing.T) {
+				if err := os.WriteFile(secretFile, []byte("second\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				// the same size, so only the modification time tells the change
+				future := time.Now().Add(time.Hour)
+				if err := os.Chtimes(secretFile, future, future); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "Bearer second",
+		},
+		{
+			name: "symlink swapped like a Kubernetes secret",
+			rotate: func(t *testing.T) {
+				target := filepath.Join(dir, "token-v3")
+				if err := os.WriteFile(target, []byte("rotated-third\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				link := filepath.Join(dir, "token.tmp")
+				if err := os.Symlink(target, link); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Rename(link, secretFile); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "Bearer rotated-third",
+		},
+	}
+
+	for _, step := range steps {
+		if step.rotate != nil {
+			step.rotate(t)
+		}
+		got, err := header.render(templateData{})
+		if err != nil {
+			t.Fatalf("%s: render() error = %v", step.name, err)
+		}
+		if got != step.want {
+			t.Errorf("%s: render() = %q, want %q", step.name, got, step.want)
+		}
+	}
+}
+
+func TestHeaderMapping(t *testing.T) {
+	msg := kafka.Message{
+		Topic: "orders",
+		Key:   []byte("order-1"),
+		Headers: []kafka.Header{
+			{Key: "id", Value: []byte("1")},
+			{Key: "trace id", Value: []byte("abc")},
+			{Key: "tenant", Value: []byte("acme")},
+			{Key: "x-internal-secret", Value: []byte("s3cr3t")},
+			{Key: "checksum", Value: []byte{0xff, 0x00, 0x01}},
+		},
+	}
+	keyHeader := "X-Kafka-Key"
+	noKeyHeader := ""
+	noDeny := []string{}
+
+	tests := []struct {
+		name      string
+		keyHeader *string
+		conf      config.HttpKafkaHeadersConfig
+		want      []headerValue
+	}{
+		{
+			name: "default",
+			want: []headerValue{
+				{key: "kafka_key", value: "order-1"},
+				{key: "trace-id", value: "abc"},
+				{key: "tenant", value: "acme"},
+				{key: "x-internal-secret", value: "s3cr3t"},
+				{key: "checksum", value: "/wAB"},
+			},
+		},
+		{
+			name:      "allow, deny, rename and prefix",
+			keyHeader: &keyHeader,
+			conf: config.HttpKafkaHeadersConfig{
+				Allow:  []string{"trace*", "tenant", "x-*", "id"},
+				Deny:   &[]string{"x-internal-*"},
+				Rename: []string{"tenant: X-Tenant"},
+				Prefix: "X-Kafka-",
+			},
+			want: []headerValue{
+				{key: "X-Kafka-Key", value: "order-1"},
+				{key: "X-Kafka-id", value: "1"},
+				{key: "X-Kafka-trace-id", value: "abc"},
+				{key: "X-Tenant", value: "acme"},
+			},
+		},
+		{
+			name:      "templates and dropped binary values",
+			keyHeader: &noKeyHeader,
+			conf: config.HttpKafkaHeadersConfig{
+				Allow:     []string{"tenant", "checksum"},
+				Deny:      &noDeny,
+				Templates: []string{"tenant: {{ .Topic }}/{{ .Value }}"},
+				Binary:    BinaryHeaderDrop,
+			},
+			want: []headerValue{
+				{key: "tenant", value: "orders/acme"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := newHeaderMapping(tt.keyHeader, tt.conf)
+			if err != nil {
+				t.Fatalf("newHeaderMapping() error = %v", err)
+			}
+
+			got, err := mapping.apply(msg)
+			if err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
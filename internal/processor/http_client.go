@@ -0,0 +1,49 @@
+package processor
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+// newHTTPClient creates the resty client with the configured timeouts and connection pool,
+// so a hung endpoint fails the message instead of stalling the consumer.
+func newHTTPClient(conf config.HttpClientConfig) (*resty.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if conf.Proxy != "" {
+		proxyURL, err := url.Parse(conf.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTTP proxy URL: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   conf.ConnectTimeout,
+		KeepAlive: conf.KeepAlive,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   conf.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: conf.ResponseHeaderTimeout,
+		IdleConnTimeout:       conf.IdleConnTimeout,
+		MaxIdleConns:          conf.MaxIdleConns,
+		MaxIdleConnsPerHost:   conf.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       conf.MaxConnsPerHost,
+		DisableKeepAlives:     conf.DisableKeepAlives,
+		ForceAttemptHTTP2:     conf.HTTP2,
+	}
+	if !conf.HTTP2 {
+		// a non-nil empty map disables the HTTP/2 upgrade over TLS
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return resty.New().SetTransport(transport).SetTimeout(conf.Timeout), nil
+}
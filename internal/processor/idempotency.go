@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+// Idempotency key sources supported by IDEMPOTENCY_SOURCE.
+const (
+	IdempotencySourceCoordinates = "coordinates"
+	IdempotencySourceHash        = "hash"
+	IdempotencySourceField       = "field"
+)
+
+// idempotency renders the idempotency key header, see config.IdempotencyConfig.
+type idempotency struct {
+	header string
+	source string
+	field  []string
+}
+
+// newIdempotency returns nil when no header is configured.
+func newIdempotency(conf config.IdempotencyConfig) (*idempotency, error) {
+	if conf.Header == "" {
+		return nil, nil
+	}
+	if !isValidHeaderName(conf.Header) {
+		return nil, fmt.Errorf("invalid idempotency header name %q", conf.Header)
+	}
+
+	i := &idempotency{header: conf.Header, source: conf.Source}
+	switch conf.Source {
+	case "", IdempotencySourceCoordinates:
+		i.source = IdempotencySourceCoordinates
+	case IdempotencySourceHash:
+	case IdempotencySourceField:
+		if conf.Field == "" {
+			return nil, fmt.Errorf("idempotency field is required for the field source")
+		}
+		i.field = strings.Split(conf.Field, ".")
+	default:
+		return nil, fmt.Errorf("invalid idempotency source: %s. Allowed sources: coordinates, hash, field", conf.Source)
+	}
+
+	return i, nil
+}
+
+// key returns the idempotency key of the message, value is the decoded value.
+//   - coordinates: "<topic>-<partition>-<offset>"
+//   - hash: hex SHA-256 of the message key and value, a JSON value is compacted first
+//     so the value kept in the error topic hashes the same on replay
+//   - field: the value of the dot separated field path of the decoded JSON value
+func (i *idempotency) key(msg kafka.Message, value []byte) (string, error) {
+	switch i.source {
+	case IdempotencySourceHash:
+		raw := msg.Value
+		var compacted bytes.Buffer
+		if json.Compact(&compacted, raw) == nil {
+			raw = compacted.Bytes()
+		}
+
+		h := sha256.New()
+		h.Write(msg.Key)
+		h.Write([]byte{0})
+		h.Write(raw)
+		return hex.EncodeToString(h.Sum(nil)), nil
+	case IdempotencySourceField:
+		key, err := jsonField(value, i.field)
+		if err != nil {
+			return "", fmt.Errorf("idempotency key: %w", err)
+		}
+		return key, nil
+	default:
+		return fmt.Sprintf("%s-%d-%d", msg.Topic, msg.Partition, msg.Offset), nil
+	}
+}
+
+// render returns the idempotency header of the message.
+func (i *idempotency) render(msg kafka.Message, value []byte) (headerValue, error) {
+	key, err := i.key(msg, value)
+	if err != nil {
+		return headerValue{}, err
+	}
+	return headerValue{key: i.header, value: key}, nil
+}
+
+// jsonField reads the scalar at the path of the JSON document, numbers are kept as written.
+func jsonField(value []byte, path []string) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return "", fmt.Errorf("field %s: value is not valid JSON: %w", strings.Join(path, "."), err)
+	}
+
+	for _, name := range path {
+		object, ok := doc.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("field %s not found", strings.Join(path, "."))
+		}
+		if doc, ok = object[name]; !ok {
+			return "", fmt.Errorf("field %s not found", strings.Join(path, "."))
+		}
+	}
+
+	switch field := doc.(type) {
+	case string:
+		if field == "" {
+			return "", fmt.Errorf("field %s is empty", strings.Join(path, "."))
+		}
+		return field, nil
+	case json.Number:
+		return field.String(), nil
+	case bool:
+		return fmt.Sprint(field), nil
+	default:
+		return "", fmt.Errorf("field %s is not a string or number", strings.Join(path, "."))
+	}
+}
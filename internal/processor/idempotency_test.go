@@ -0,0 +1,91 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	msg := kafka.Message{
+		Topic:     "orders",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("order-1"),
+		Value:     []byte(`{"order": {"id": 1001, "ref": "A-1"}}`),
+	}
+	value := []byte(`{"order": {"id": 1001, "ref": "A-1"}}`)
+
+	// the error topic keeps the value compacted, replays must hash the same
+	replayed := msg
+	replayed.Value = []byte(`{"order":{"id":1001,"ref":"A-1"}}`)
+
+	tests := []struct {
+		name    string
+		conf    config.IdempotencyConfig
+		msg     kafka.Message
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "coordinates",
+			conf: config.IdempotencyConfig{Header: "Idempotency-Key", Source: IdempotencySourceCoordinates},
+			msg:  msg,
+			want: "orders-2-42",
+		},
+		{
+			name: "hash",
+			conf: config.IdempotencyConfig{Header: "Idempotency-Key", Source: IdempotencySourceHash},
+			msg:  msg,
+			want: "fa9629b3e06a02972465b687daa4e1b5ac30589ed144285d30642a3f137b2cec",
+		},
+		{
+			name: "hash of replayed message",
+			conf: config.IdempotencyConfig{Header: "Idempotency-Key", Source: IdempotencySourceHash},
+			msg:  replayed,
+			want: "fa9629b3e06a02972465b687daa4e1b5ac30589ed144285d30642a3f137b2cec",
+		},
+		{
+			name: "string field",
+			conf: config.IdempotencyConfig{Header: "Idempotency-Key", Source: IdempotencySourceField, Field: "order.ref"},
+			msg:  msg,
+			want: "A-1",
+		},
+		{
+			name: "number field",
+			conf: config.IdempotencyConfig{Header: "Idempotency-Key", Source: IdempotencySourceField, Field: "order.id"},
+			msg:  msg,
+			want: "1001",
+		},
+		{
+			name:    "missing field",
+			conf:    config.IdempotencyConfig{Header: "Idempotency-Key", Source: IdempotencySourceField, Field: "order.customer"},
+			msg:     msg,
+			wantErr: true,
+		},
+		{
+			name:    "object field",
+			conf:    config.IdempotencyConfig{Header: "Idempotency-Key", Source: IdempotencySourceField, Field: "order"},
+			msg:     msg,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := newIdempotency(tt.conf)
+			if err != nil {
+				t.Fatalf("newIdempotency() error = %v", err)
+			}
+
+			got, err := i.key(tt.msg, value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("key() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,40 +5,39 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/url"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
-	"github.com/go-resty/resty/v2"
 	"github.com/linkedin/goavro/v2"
-	"github.com/pkg/errors"
 	"github.com/riferrei/srclient"
 	"github.com/segmentio/kafka-go"
 	"github.com/urbanindo/go-kafka-http-sink/config"
+	"github.com/urbanindo/go-kafka-http-sink/internal/kafkaclient"
 	"go.uber.org/zap"
 )
 
-type httpHeader struct {
-	key   string
-	value string
-}
-
-type httpProcessor struct {
-	http          *resty.Client
-	sr            *srclient.SchemaRegistryClient
-	url           string
-	method        string
-	pathParam     *string
+// messageProcessor decodes the consumed messages and delivers them with the sink.
+// Failures are written to the error topic and responses to the success topic when configured.
+type messageProcessor struct {
+	sr *srclient.SchemaRegistryClient
+	// mu guards sink, it is held while sending so a replaced sink is only closed
+	// once the in-flight message is done with it.
+	mu            sync.RWMutex
+	sink          Sink
 	logr          *zap.Logger
-	headers       []httpHeader
 	errorWriter   ErrorWriter
-	successWriter *kafka.Writer
+	successWriter kafkaclient.Producer
+	success       *successFormat
+	dedup         *deduplicator
 }
 
-func NewProcessor(conf *config.Config, logr *zap.Logger, errorWriter *kafka.Writer, successWriter *kafka.Writer) httpProcessor {
-	r := resty.New()
-	headers := []httpHeader{}
+// NewProcessor creates the processor of the named pipeline for the configured sink, the writers
+// are optional and must be untyped nil when the success or error topic is not configured.
+func NewProcessor(name string, conf *config.Config, logr *zap.Logger, errorWriter kafkaclient.Producer, successWriter kafkaclient.Producer) *messageProcessor {
 	var schemaRegistryClient *srclient.SchemaRegistryClient
 
 	if conf.KafkaConfig.SchemaRegistryUrl != nil {
@@ -47,161 +46,173 @@ func NewProcessor(conf *config.Config, logr *zap.Logger, errorWriter *kafka.Writ
 		)
 	}
 
-	if conf.HttpHeaders != nil {
-		for _, head := range *conf.HttpHeaders {
-			heads := strings.Split(head, ":")
-			if len(heads) < 2 {
-				panic("header should have key and value")
-			}
-			headers = append(headers, httpHeader{
-				key:   heads[0],
-				value: strings.Join(heads[1:], ""),
-			})
-		}
+	sink, err := NewSink(name, conf, logr)
+	if err != nil {
+		panic(err.Error())
 	}
 
-	// Set HTTP method, default to POST if not specified
-	method := "POST"
-	if conf.HttpMethod != nil {
-		method = strings.ToUpper(*conf.HttpMethod)
-		// Validate allowed HTTP methods
-		validMethods := map[string]bool{
-			"POST":   true,
-			"PUT":    true,
-			"PATCH":  true,
-			"DELETE": true,
-		}
-		if !validMethods[method] {
-			panic(fmt.Sprintf("invalid HTTP method: %s. Allowed methods: POST, PUT, PATCH, DELETE", method))
-		}
+	success, err := newSuccessFormat(conf.Success)
+	if err != nil {
+		panic(err.Error())
 	}
 
-	return httpProcessor{
-		http:          r,
-		url:           conf.HttpApiUrl,
-		method:        method,
-		pathParam:     conf.HttpPathParam,
-		headers:       headers,
-		logr:          logr,
+	var eWriter ErrorWriter
+	if errorWriter != nil {
+		eWriter = NewErrorWriter(errorWriter)
+	}
+
+	return &messageProcessor{
 		sr:            schemaRegistryClient,
-		errorWriter:   NewErrorWriter(errorWriter),
+		sink:          sink,
+		logr:          logr,
+		errorWriter:   eWriter,
 		successWriter: successWriter,
+		success:       success,
+		dedup:         newDeduplicator(name, conf, logr),
 	}
 }
 
-// parseURL builds the final URL with path parameter substitution if configured.
-// It validates and sanitizes the message key, URL-encodes it, and substitutes it into the base URL.
-// Returns error if key is empty after sanitization or placeholder is not found in URL.
-func (h *httpProcessor) parseURL(msgKey []byte) (string, error) {
-	// If no path parameter is configured, return base URL
-	if h.pathParam == nil {
-		return h.url, nil
+func (h *messageProcessor) Process(ctx context.Context, msg kafka.Message) error {
+	value, err := h.decode(msg)
+	if err != nil {
+		return h.fail(ctx, msg, newErrorPayload(msg, ErrorClassDecode, err), err)
 	}
 
-	// Sanitize the message key
-	sanitizedKey := sanitizeKey(msgKey)
-	if sanitizedKey == "" {
-		err := fmt.Errorf("message key is empty after sanitization, cannot substitute path parameter %s", *h.pathParam)
-		return "", err
+	dedupKey, duplicate := h.dedup.seen(ctx, msg, value)
+	if duplicate {
+		return nil
 	}
 
-	// URL-encode the sanitized key
-	encodedKey := url.PathEscape(sanitizedKey)
-
-	// Substitute the path parameter
-	finalURL, err := substitutePathParam(h.url, *h.pathParam, encodedKey)
+	start := time.Now()
+	h.mu.RLock()
+	delivery, err := h.sink.Send(ctx, msg, value)
+	h.mu.RUnlock()
 	if err != nil {
-		return "", fmt.Errorf("failed to substitute path parameter: %w", err)
+		return h.fail(ctx, msg, newDeliveryErrorPayload(msg, value, err), err)
 	}
+	latency := time.Since(start)
+	h.dedup.delivered(ctx, dedupKey)
 
-	return finalURL, nil
-}
+	if h.successWriter == nil {
+		return nil
+	}
 
-func (h *httpProcessor) Process(ctx context.Context, msg kafka.Message) error {
-	var value []byte
-	var err error
-	if h.sr != nil {
-		value, err = convertFromSchemaRegistry(h.sr, msg)
+	success, err := h.success.message(msg, value, delivery, latency)
+	if err != nil {
+		return err
+	}
+	return h.successWriter.WriteMessages(ctx, success)
+}
 
-		if err != nil {
-			return err
-		}
-	} else if isOtherDecoderbufsFormat(msg.Value) {
-		// If Schema Registry is not available, check if the message is in decoderbufs format
-		// Sanitize the payload (remove leading null bytes and re-serialize to clean JSON)
-		value, err = sanitizePayload(msg.Value)
-		if err != nil {
-			return fmt.Errorf("payload sanitization failed: %v", err)
-		}
+// Inspect describes what the sink would send for the message without sending it.
+// Failures are reported in the inspection instead of an error.
+func (h *messageProcessor) Inspect(msg kafka.Message) *Inspection {
+	inspection := &Inspection{Headers: map[string]string{}}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if value, err := h.decode(msg); err != nil {
+		inspection.Error = err.Error()
+		inspection.ErrorClass = ErrorClassDecode
+	} else if sinkInspection, err := h.sink.Inspect(msg, value); err != nil {
+		inspection.Error = err.Error()
+		inspection.ErrorClass = newDeliveryErrorPayload(msg, value, err).ErrorClass
 	} else {
-		value = msg.Value
+		inspection = sinkInspection
 	}
 
-	r := h.http.NewRequest().SetContext(ctx)
+	inspection.Topic = msg.Topic
+	inspection.Partition = msg.Partition
+	inspection.Offset = msg.Offset
+	return inspection
+}
+
+// Close releases the sink connections and the dedup store.
+func (h *messageProcessor) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return errors.Join(h.sink.Close(), h.dedup.close())
+}
+
+// SetSink replaces the sink used for the next messages, e.g. on config reload,
+// and closes the previous one once the in-flight message is sent.
+func (h *messageProcessor) SetSink(sink Sink) error {
+	h.mu.Lock()
+	previous := h.sink
+	h.sink = sink
+	h.mu.Unlock()
+
+	return previous.Close()
+}
+
+// Endpoints returns the state of the endpoints of the current sink, nil when it has none.
+func (h *messageProcessor) Endpoints() []EndpointState {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 
-	for _, header := range h.headers {
-		r.SetHeader(header.key, header.value)
+	if sink, ok := h.sink.(*httpSink); ok {
+		return sink.balancer.states()
 	}
+	return nil
+}
 
-	r.SetHeader("kafka_key", sanitizeKey(msg.Key))
+// decode converts the message value into the JSON request body.
+func (h *messageProcessor) decode(msg kafka.Message) ([]byte, error) {
+	if h.sr != nil {
+		return convertFromSchemaRegistry(h.sr, msg)
+	}
 
-	for _, msgHeader := range msg.Headers {
-		// based on existing logic no need to add id header
-		if msgHeader.Key != "id" {
-			r.SetHeader(msgHeader.Key, string(msgHeader.Value))
+	if isOtherDecoderbufsFormat(msg.Value) {
+		// If Schema Registry is not available, check if the message is in decoderbufs format
+		// Sanitize the payload (remove leading null bytes and re-serialize to clean JSON)
+		value, err := sanitizePayload(msg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("payload sanitization failed: %v", err)
 		}
+		return value, nil
 	}
 
-	// Set request body once
-	r.SetBody(value)
+	return msg.Value, nil
+}
 
-	// Build final URL with path parameter substitution if configured
-	finalURL, err := h.parseURL(msg.Key)
-	if err != nil {
-		return err
+// newDeliveryErrorPayload builds the error payload from the sink error,
+// errors without destination context are classified as transport errors.
+func newDeliveryErrorPayload(msg kafka.Message, value []byte, err error) *ErrorPayload {
+	var deliveryErr *DeliveryError
+	if !errors.As(err, &deliveryErr) {
+		payload := newErrorPayload(msg, ErrorClassTransport, err)
+		payload.setRequestBody(value)
+		return payload
 	}
 
-	// Execute HTTP request based on configured method
-	var res *resty.Response
-	switch h.method {
-	case "POST":
-		res, err = r.Post(finalURL)
-	case "PUT":
-		res, err = r.Put(finalURL)
-	case "PATCH":
-		res, err = r.Patch(finalURL)
-	case "DELETE":
-		res, err = r.Delete(finalURL)
-	default:
-		return fmt.Errorf("unsupported HTTP method: %s", h.method)
-	}
+	payload := newErrorPayload(msg, deliveryErr.Class, err)
+	payload.URL = deliveryErr.URL
+	payload.Attempts = deliveryErr.Attempts
+	payload.ResponseCode = deliveryErr.StatusCode
+	payload.ResponseBody = string(deliveryErr.Body)
+	payload.setRequestBody(value)
+	return payload
+}
 
-	if err != nil {
-		return err
+// fail writes the failure context to the error topic when configured and returns the cause,
+// so every failure kind is routable regardless of where it happened.
+func (h *messageProcessor) fail(ctx context.Context, msg kafka.Message, payload *ErrorPayload, cause error) error {
+	if h.errorWriter == nil {
+		return cause
 	}
 
-	if res.StatusCode() >= 300 && h.errorWriter != nil {
-		if err := h.errorWriter.WriteError(ctx, msg.Key, &ErrorPayload{
-			ResponseBody:    string(res.Body()),
-			ResponseCode:    res.StatusCode(),
-			RequestBodyJSON: value,
-		}); err != nil {
-			return fmt.Errorf("error when writing to error topic: %v", err)
-		}
-		return fmt.Errorf("error from http with status code '%d': %s", res.StatusCode(), string(res.Body()))
+	if err := h.errorWriter.WriteError(ctx, msg.Key, payload); err != nil {
+		return fmt.Errorf("error when writing to error topic: %v", err)
 	}
 
-	h.logr.Debug("got " + res.Status() + " with body " + string(res.Body()))
-	if h.successWriter != nil {
-		return h.successWriter.WriteMessages(ctx, kafka.Message{
-			Key:   msg.Key,
-			Value: res.Body(),
-		})
-	}
-	return nil
+	return cause
 }
 
 func convertFromSchemaRegistry(sr *srclient.SchemaRegistryClient, msg kafka.Message) ([]byte, error) {
+	if len(msg.Value) < 5 {
+		return nil, fmt.Errorf("message value is too short for schema registry wire format")
+	}
 	schemaID := binary.BigEndian.Uint32(msg.Value[1:5])
 	schema, err := sr.GetSchema(int(schemaID))
 	if err != nil {
@@ -224,42 +235,6 @@ func convertFromSchemaRegistry(sr *srclient.SchemaRegistryClient, msg kafka.Mess
 	return jsonStr, nil
 }
 
-type ErrorPayload struct {
-	ResponseBody    string          `json:"response_body"`
-	ResponseCode    int             `json:"response_code"`
-	RequestBodyJSON json.RawMessage `json:"request_body_json"`
-}
-
-type ErrorWriter interface {
-	WriteError(ctx context.Context, key []byte, errPayload *ErrorPayload) error
-}
-
-func NewErrorWriter(kafkaWriter *kafka.Writer) ErrorWriter {
-	return &errorWriter{
-		writer: kafkaWriter,
-	}
-}
-
-type errorWriter struct {
-	writer *kafka.Writer
-}
-
-func (e *errorWriter) WriteError(ctx context.Context, key []byte, errPayload *ErrorPayload) error {
-	value, err := json.Marshal(errPayload)
-	if err != nil {
-		return errors.Wrap(err, "WriteError: failed to marshal payload")
-	}
-
-	if err := e.writer.WriteMessages(ctx, kafka.Message{
-		Key:   key,
-		Value: value,
-	}); err != nil {
-		return errors.Wrap(err, "WriteError: failed write to kafka")
-	}
-
-	return nil
-}
-
 func sanitizePayload(value []byte) ([]byte, error) {
 	trimmedValue := bytes.TrimLeftFunc(value, func(r rune) bool {
 		// Remove everything until we reach '{' or '[' that come from header of []byte to get exact body
@@ -1,7 +1,17 @@
 package processor
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"text/template"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
 )
 
 func TestSubstitutePathParam(t *testing.T) {
@@ -192,13 +202,13 @@ func TestParseURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			processor := &httpProcessor{
+			processor := &httpSink{
 				url:       tt.baseURL,
 				pathParam: tt.pathParam,
 				logr:      nil, // Using nil logger for test simplicity
 			}
 
-			gotURL, err := processor.parseURL(tt.msgKey)
+			gotURL, err := processor.parseURL(kafka.Message{Key: tt.msgKey})
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseURL() error = %v, wantErr %v", err, tt.wantErr)
@@ -218,7 +228,233 @@ func TestParseURL(t *testing.T) {
 	}
 }
 
+func TestParseURLTemplate(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseURL   string
+		pathParam *string
+		msg       kafka.Message
+		wantURL   string
+	}{
+		{
+			name:    "topic in URL",
+			baseURL: "http://api.com/v1/{{ .Topic }}/events",
+			msg:     kafka.Message{Topic: "orders", Key: []byte("user123")},
+			wantURL: "http://api.com/v1/orders/events",
+		},
+		{
+			name:      "topic with path parameter",
+			baseURL:   "http://api.com/v1/{{ .Topic }}/:id",
+			pathParam: stringPtr(":id"),
+			msg:       kafka.Message{Topic: "users", Key: []byte("user123")},
+			wantURL:   "http://api.com/v1/users/user123",
+		},
+		{
+			name:    "key cannot change the path or query",
+			baseURL: "http://api.com/v1/users/{{ .Key }}/events",
+			msg:     kafka.Message{Topic: "users", Key: []byte("../admin?drop=1#x")},
+			wantURL: "http://api.com/v1/users/..%2Fadmin%3Fdrop=1%23x/events",
+		},
+		{
+			name:    "dot segment key",
+			baseURL: "http://api.com/v1/users/{{ .Key }}",
+			msg:     kafka.Message{Topic: "users", Key: []byte("..")},
+			wantURL: "http://api.com/v1/users/%2E%2E",
+		},
+		{
+			name:    "header value",
+			baseURL: "http://api.com/v1/tenants/{{ .Headers.tenant }}/orders",
+			msg:     kafka.Message{Topic: "orders", Headers: []kafka.Header{{Key: "tenant", Value: []byte("a/b")}}},
+			wantURL: "http://api.com/v1/tenants/a%2Fb/orders",
+		},
+		{
+			name:      "dot segment path parameter",
+			baseURL:   "http://api.com/v1/users/:id",
+			pathParam: stringPtr(":id"),
+			msg:       kafka.Message{Topic: "users", Key: []byte(".")},
+			wantURL:   "http://api.com/v1/users/%2E",
+		},
+		{
+			name:    "escape functions",
+			baseURL: `http://api.com/v1/{{ pathEscape "a b/c" }}?q={{ queryEscape "a&b" }}`,
+			msg:     kafka.Message{Topic: "users"},
+			wantURL: "http://api.com/v1/a%20b%2Fc?q=a%26b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := &httpSink{
+				url:         tt.baseURL,
+				urlTemplate: template.Must(template.New("url").Funcs(templateFuncs()).Parse(tt.baseURL)),
+				pathParam:   tt.pathParam,
+			}
+
+			gotURL, err := processor.parseURL(tt.msg)
+			if err != nil {
+				t.Errorf("parseURL() error = %v", err)
+				return
+			}
+
+			if gotURL != tt.wantURL {
+				t.Errorf("parseURL() got = %q, want %q", gotURL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestProcessErrorPayload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"error":"invalid"}`))
+	}))
+	defer server.Close()
+
+	closedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedServer.Close()
+
+	msgTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name      string
+		url       string
+		value     []byte
+		wantClass string
+		wantCode  int
+		wantJSON  bool
+	}{
+		{
+			name:      "http error",
+			url:       server.URL,
+			value:     []byte(`{"id":1}`),
+			wantClass: ErrorClassHTTP,
+			wantCode:  http.StatusUnprocessableEntity,
+			wantJSON:  true,
+		},
+		{
+			name:      "transport error",
+			url:       closedServer.URL,
+			value:     []byte(`{"id":1}`),
+			wantClass: ErrorClassTransport,
+			wantJSON:  true,
+		},
+		{
+			name:      "decode error",
+			url:       server.URL,
+			value:     []byte("\x00\x00\x00\x00\x00not-json"),
+			wantClass: ErrorClassDecode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errWriter := &fakeErrorWriter{}
+			processor := &messageProcessor{
+				sink: &httpSink{
+					http:   resty.New(),
+					url:    tt.url,
+					method: "POST",
+					logr:   zap.NewNop(),
+				},
+				logr:        zap.NewNop(),
+				errorWriter: errWriter,
+			}
+
+			err := processor.Process(context.Background(), kafka.Message{
+				Topic:     "orders",
+				Partition: 2,
+				Offset:    42,
+				Time:      msgTime,
+				Key:       []byte("key"),
+				Value:     tt.value,
+				Headers:   []kafka.Header{{Key: "trace", Value: []byte("abc")}},
+			})
+			if err == nil {
+				t.Fatal("Process() should return error")
+			}
+
+			if len(errWriter.payloads) != 1 {
+				t.Fatalf("Process() wrote %d error payloads, want 1", len(errWriter.payloads))
+			}
+
+			payload := errWriter.payloads[0]
+			if payload.ErrorClass != tt.wantClass {
+				t.Errorf("ErrorClass = %q, want %q", payload.ErrorClass, tt.wantClass)
+			}
+			if payload.Topic != "orders" || payload.Partition != 2 || payload.Offset != 42 || !payload.Timestamp.Equal(msgTime) {
+				t.Errorf("coordinates = %s/%d/%d@%s, want orders/2/42@%s", payload.Topic, payload.Partition, payload.Offset, payload.Timestamp, msgTime)
+			}
+			if payload.ResponseCode != tt.wantCode {
+				t.Errorf("ResponseCode = %d, want %d", payload.ResponseCode, tt.wantCode)
+			}
+			if len(payload.Headers) != 1 || payload.Headers[0].Key != "trace" {
+				t.Errorf("Headers = %v, want trace header", payload.Headers)
+			}
+			if tt.wantJSON != (payload.OriginalValue != nil) || tt.wantJSON == (payload.OriginalValueBase64 != "") {
+				t.Errorf("OriginalValue = %s, OriginalValueBase64 = %q, wantJSON %v", payload.OriginalValue, payload.OriginalValueBase64, tt.wantJSON)
+			}
+			if tt.wantClass != ErrorClassDecode && (payload.URL != tt.url || payload.Attempts != 1) {
+				t.Errorf("URL = %q, Attempts = %d, want %q and 1", payload.URL, payload.Attempts, tt.url)
+			}
+			if _, err := json.Marshal(payload); err != nil {
+				t.Errorf("payload should be marshallable: %v", err)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	t.Setenv("INSPECT_TEST_TOKEN", "s3cr3t")
+	funcs := templateFuncs()
+	secret, err := parseHeaderSpec(`Authorization: Bearer {{ env "INSPECT_TEST_TOKEN" }}`, funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := parseHeaderSpec("X-Source: {{ .Topic }}", funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processor := &messageProcessor{sink: &httpSink{
+		url:       "http://api.com/v1/users/:id",
+		method:    "PUT",
+		pathParam: stringPtr(":id"),
+		headers:   []httpHeader{secret, source},
+	}}
+
+	got := processor.Inspect(kafka.Message{Topic: "users", Key: []byte("user123"), Value: []byte(`{"name":"a"}`)})
+
+	if got.Error != "" {
+		t.Fatalf("Inspect() error = %s", got.Error)
+	}
+	if got.Method != "PUT" || got.URL != "http://api.com/v1/users/user123" {
+		t.Errorf("Inspect() = %s %s, want PUT http://api.com/v1/users/user123", got.Method, got.URL)
+	}
+	if got.Headers["Authorization"] != redactedValue {
+		t.Errorf("Inspect() Authorization = %q, should be redacted", got.Headers["Authorization"])
+	}
+	if got.Headers["X-Source"] != "users" || got.Headers["kafka_key"] != "user123" {
+		t.Errorf("Inspect() headers = %v", got.Headers)
+	}
+	if string(got.Body) != `{"name":"a"}` {
+		t.Errorf("Inspect() body = %s", got.Body)
+	}
+
+	failed := processor.Inspect(kafka.Message{Topic: "users", Key: []byte("\x00"), Value: []byte(`{}`)})
+	if failed.ErrorClass != ErrorClassRequest {
+		t.Errorf("Inspect() error class = %q, want %q", failed.ErrorClass, ErrorClassRequest)
+	}
+}
+
 // Helper functions for tests
+type fakeErrorWriter struct {
+	payloads []*ErrorPayload
+}
+
+func (f *fakeErrorWriter) WriteError(ctx context.Context, key []byte, errPayload *ErrorPayload) error {
+	f.payloads = append(f.payloads, errPayload)
+	return nil
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
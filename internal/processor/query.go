@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+// defaultMaxURLLength is the URL length most servers and proxies accept.
+const defaultMaxURLLength = 8192
+
+// parseQueryParams parses the specs of HTTP_QUERY.
+func parseQueryParams(specs []string) ([]config.QueryParam, error) {
+	params := make([]config.QueryParam, 0, len(specs))
+	for _, spec := range specs {
+		param, err := config.ParseQueryParam(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query parameter: %w", err)
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// addQuery appends the query parameters from the decoded value to the URL, the existing
+// query of the URL is kept as is. Arrays are repeated parameters, objects are compact JSON.
+func addQuery(rawURL string, params []config.QueryParam, value []byte) (string, error) {
+	if len(params) == 0 {
+		return rawURL, nil
+	}
+
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return "", fmt.Errorf("query parameters require a JSON value: %w", err)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+
+	query := url.Values{}
+	for _, param := range params {
+		field, ok := lookupField(doc, param.Path)
+		if !ok {
+			continue
+		}
+
+		values, ok := field.([]interface{})
+		if !ok {
+			values = []interface{}{field}
+		}
+		for _, v := range values {
+			text, err := queryValue(v)
+			if err != nil {
+				return "", fmt.Errorf("query parameter %s: %w", param.Name, err)
+			}
+			query.Add(param.Name, text)
+		}
+	}
+	if len(query) == 0 {
+		return rawURL, nil
+	}
+	if u.RawQuery != "" {
+		u.RawQuery += "&" + query.Encode()
+	} else {
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String(), nil
+}
+
+func queryValue(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		return fmt.Sprint(value), nil
+	default:
+		content, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	}
+}
@@ -0,0 +1,60 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+// response reads the mapping sources from a delivery, the body is decoded on first use.
+type response struct {
+	delivery *Delivery
+	body     interface{}
+	decoded  bool
+}
+
+// lookup returns the value of the source, a response header is a string
+// and a body field keeps its JSON type with numbers as json.Number.
+func (r *response) lookup(src config.MappingSource) (interface{}, bool) {
+	if src.Header != "" {
+		values := lookupHeader(r.delivery.Headers, src.Header)
+		if len(values) == 0 {
+			return nil, false
+		}
+		return strings.Join(values, ", "), true
+	}
+
+	if !r.decoded {
+		r.decoded = true
+		decoder := json.NewDecoder(bytes.NewReader(r.delivery.Body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&r.body); err != nil {
+			r.body = nil
+		}
+	}
+
+	return lookupField(r.body, src.Path)
+}
+
+// lookupString returns the value of the source as text, objects and arrays as compact JSON.
+func (r *response) lookupString(src config.MappingSource) (string, bool) {
+	value, ok := r.lookup(src)
+	if !ok {
+		return "", false
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	default:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return string(content), true
+	}
+}
@@ -0,0 +1,151 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"go.uber.org/zap"
+)
+
+// Sink types supported by SINK_TYPE.
+const (
+	SinkTypeHTTP   = "http"
+	SinkTypeGRPC   = "grpc"
+	SinkTypeFile   = "file"
+	SinkTypeStdout = "stdout"
+)
+
+// Sink delivers a decoded message to its destination. Consumption, decoding and
+// the success and error topics are handled by the processor for every sink.
+type Sink interface {
+	// Send delivers the decoded value. Failures should be returned as *DeliveryError
+	// so the error topic record has the destination context.
+	Send(ctx context.Context, msg kafka.Message, value []byte) (*Delivery, error)
+	// Inspect describes what Send would deliver without sending it.
+	Inspect(msg kafka.Message, value []byte) (*Inspection, error)
+	Close() error
+}
+
+// Delivery is the result of a successful Send.
+type Delivery struct {
+	// Body is the response written to the success topic, the written line for the file and stdout sinks.
+	Body       []byte
+	StatusCode int
+	URL        string
+	Attempts   int
+	// Headers are the response headers, or the response metadata for gRPC.
+	Headers map[string][]string
+}
+
+// DeliveryError is a failed Send with the destination context.
+type DeliveryError struct {
+	Class      string
+	URL        string
+	Attempts   int
+	StatusCode int
+	Body       []byte
+	Err        error
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// Inspection is what the sink would send for a message, used by dry-run mode.
+// Body is kept as JSON when valid, otherwise as text in BodyText.
+type Inspection struct {
+	Topic      string            `json:"topic"`
+	Partition  int               `json:"partition"`
+	Offset     int64             `json:"offset"`
+	Method     string            `json:"method"`
+	URL        string            `json:"url"`
+	Headers    map[string]string `json:"headers"`
+	Body       json.RawMessage   `json:"body,omitempty"`
+	BodyText   string            `json:"body_text,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	ErrorClass string            `json:"error_class,omitempty"`
+}
+
+func (i *Inspection) setBody(value []byte) {
+	if json.Valid(value) {
+		i.Body = value
+	} else {
+		i.BodyText = string(value)
+	}
+}
+
+// NewSink creates the sink configured by SINK_TYPE, HTTP by default.
+func NewSink(name string, conf *config.Config, logr *zap.Logger) (Sink, error) {
+	switch conf.Sink.Type {
+	case "", SinkTypeHTTP:
+		return NewHTTPSink(name, conf, logr)
+	case SinkTypeGRPC:
+		return NewGRPCSink(conf.Sink.Grpc, conf.Idempotency, logr)
+	case SinkTypeFile:
+		return NewFileSink(conf.Sink.FilePath, conf.Sink.Envelope)
+	case SinkTypeStdout:
+		return NewStdoutSink(conf.Sink.Envelope), nil
+	default:
+		return nil, fmt.Errorf("invalid sink type: %s. Allowed types: http, grpc, file, stdout", conf.Sink.Type)
+	}
+}
+
+// headerValue is a rendered header or metadata entry.
+type headerValue struct {
+	key    string
+	value  string
+	secret bool
+}
+
+// renderHeaders renders the configured headers for the message.
+func renderHeaders(headers []httpHeader, msg kafka.Message) ([]headerValue, error) {
+	data := newTemplateData(msg)
+	rendered := make([]headerValue, 0, len(headers))
+	for _, header := range headers {
+		value, err := header.render(data)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, headerValue{key: header.key, value: value, secret: header.secret})
+	}
+	return rendered, nil
+}
+
+// parseHeaderSpecs parses and validates the header specs, see parseHeaderSpec.
+func parseHeaderSpecs(specs []string, logr *zap.Logger) ([]httpHeader, error) {
+	funcs := templateFuncs()
+	headers := []httpHeader{}
+	for _, spec := range specs {
+		header, err := parseHeaderSpec(spec, funcs)
+		if err != nil {
+			return nil, err
+		}
+		// Render once on startup so missing env vars or secret files fail fast
+		if _, err := header.render(templateData{}); err != nil {
+			return nil, err
+		}
+		logr.Debug("configured header", zap.Stringer("header", header))
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+// redactHeaders converts the headers for inspection with secret values redacted.
+func redactHeaders(headers []headerValue) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for _, header := range headers {
+		if header.secret {
+			redacted[header.key] = redactedValue
+			continue
+		}
+		redacted[header.key] = header.value
+	}
+	return redacted
+}
@@ -0,0 +1,121 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// writerSink writes every decoded value as one JSON line, used by the file and stdout sinks.
+type writerSink struct {
+	mu       sync.Mutex
+	out      io.Writer
+	closer   io.Closer
+	name     string
+	envelope bool
+}
+
+// envelope is the line written when SINK_ENVELOPE is enabled.
+type envelope struct {
+	Topic     string            `json:"topic"`
+	Partition int               `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Key       string            `json:"key"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Value     json.RawMessage   `json:"value,omitempty"`
+	ValueText string            `json:"value_text,omitempty"`
+}
+
+// NewFileSink appends to the NDJSON file at path, creating it when missing.
+func NewFileSink(path string, withEnvelope bool) (*writerSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sink file: %w", err)
+	}
+
+	return &writerSink{out: file, closer: file, name: path, envelope: withEnvelope}, nil
+}
+
+// NewStdoutSink writes to stdout, mostly useful for debugging a pipeline.
+func NewStdoutSink(withEnvelope bool) *writerSink {
+	return &writerSink{out: os.Stdout, name: "stdout", envelope: withEnvelope}
+}
+
+// line builds the line for the message, the value must be a single line so
+// non-JSON values are only allowed with the envelope.
+func (w *writerSink) line(msg kafka.Message, value []byte) ([]byte, error) {
+	if !w.envelope {
+		if !json.Valid(value) {
+			return nil, &DeliveryError{Class: ErrorClassRequest, URL: w.name, Err: fmt.Errorf("value is not valid JSON, enable SINK_ENVELOPE to write it as text")}
+		}
+		var b bytes.Buffer
+		if err := json.Compact(&b, value); err != nil {
+			return nil, &DeliveryError{Class: ErrorClassRequest, URL: w.name, Err: err}
+		}
+		return append(b.Bytes(), '\n'), nil
+	}
+
+	e := envelope{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       sanitizeKey(msg.Key),
+	}
+	if json.Valid(value) {
+		e.Value = value
+	} else {
+		e.ValueText = string(value)
+	}
+	if len(msg.Headers) > 0 {
+		e.Headers = make(map[string]string, len(msg.Headers))
+		for _, header := range msg.Headers {
+			e.Headers[header.Key] = string(header.Value)
+		}
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return nil, &DeliveryError{Class: ErrorClassRequest, URL: w.name, Err: err}
+	}
+	return append(line, '\n'), nil
+}
+
+func (w *writerSink) Send(ctx context.Context, msg kafka.Message, value []byte) (*Delivery, error) {
+	line, err := w.line(msg, value)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.out.Write(line); err != nil {
+		return nil, &DeliveryError{Class: ErrorClassTransport, URL: w.name, Attempts: 1, Err: err}
+	}
+
+	// there is no response, so the written line is the success topic value
+	return &Delivery{Body: bytes.TrimSuffix(line, []byte("\n")), URL: w.name, Attempts: 1}, nil
+}
+
+func (w *writerSink) Inspect(msg kafka.Message, value []byte) (*Inspection, error) {
+	line, err := w.line(msg, value)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &Inspection{Method: "WRITE", URL: w.name, Headers: map[string]string{}}
+	inspection.setBody(bytes.TrimSuffix(line, []byte("\n")))
+	return inspection, nil
+}
+
+func (w *writerSink) Close() error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
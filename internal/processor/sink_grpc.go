@@ -0,0 +1,257 @@
+package processor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// grpcSink calls a unary gRPC method with the decoded JSON value as request message.
+// The request and response types are resolved at runtime from the descriptor set,
+// so no generated code is needed for the destination service.
+type grpcSink struct {
+	conn        *grpc.ClientConn
+	target      string
+	method      string
+	input       protoreflect.MessageDescriptor
+	output      protoreflect.MessageDescriptor
+	metadata    []httpHeader
+	idempotency *idempotency
+	timeout     time.Duration
+	logr        *zap.Logger
+}
+
+func NewGRPCSink(conf config.GrpcSinkConfig, idempotencyConf config.IdempotencyConfig, logr *zap.Logger) (*grpcSink, error) {
+	methodDesc, err := findMethod(conf.DescriptorSet, conf.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	idempotency, err := newIdempotency(idempotencyConf)
+	if err != nil {
+		return nil, err
+	}
+
+	md, err := parseHeaderSpecs(conf.Metadata, logr)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := insecure.NewCredentials()
+	if !conf.Insecure {
+		tlsConfig, err := newGRPCTLSConfig(conf)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.NewClient(conf.Target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+
+	return &grpcSink{
+		conn:        conn,
+		target:      conf.Target,
+		method:      conf.Method,
+		input:       methodDesc.Input(),
+		output:      methodDesc.Output(),
+		metadata:    md,
+		idempotency: idempotency,
+		timeout:     conf.Timeout,
+		logr:        logr,
+	}, nil
+}
+
+// newGRPCTLSConfig loads the CA and the client certificate when configured.
+func newGRPCTLSConfig(conf config.GrpcSinkConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: conf.ServerName}
+
+	if conf.CAFile != "" {
+		ca, err := os.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gRPC CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no valid certificate found in gRPC CA file %s", conf.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (conf.CertFile == "") != (conf.KeyFile == "") {
+		return nil, fmt.Errorf("gRPC TLS client certificate requires both cert file and key file")
+	}
+
+	if conf.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load gRPC client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// findMethod loads the FileDescriptorSet and finds the unary method by its full name,
+// e.g. /orders.v1.OrderService/CreateOrder.
+func findMethod(descriptorSet, method string) (protoreflect.MethodDescriptor, error) {
+	content, err := os.ReadFile(descriptorSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %w", err)
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(content, &set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+
+	serviceName, methodName, ok := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("gRPC method %q should be in /package.Service/Method format", method)
+	}
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("gRPC service %s not found in descriptor set: %w", serviceName, err)
+	}
+	service, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a gRPC service", serviceName)
+	}
+
+	methodDesc := service.Methods().ByName(protoreflect.Name(methodName))
+	if methodDesc == nil {
+		return nil, fmt.Errorf("gRPC method %s not found in service %s", methodName, serviceName)
+	}
+	if methodDesc.IsStreamingClient() || methodDesc.IsStreamingServer() {
+		return nil, fmt.Errorf("gRPC method %s is streaming, only unary methods are supported", method)
+	}
+
+	return methodDesc, nil
+}
+
+// buildRequest converts the JSON value into the request message and renders the metadata.
+func (g *grpcSink) buildRequest(msg kafka.Message, value []byte) (*dynamicpb.Message, []headerValue, error) {
+	req := dynamicpb.NewMessage(g.input)
+	if err := protojson.Unmarshal(value, req); err != nil {
+		err = fmt.Errorf("value does not match gRPC request %s: %w", g.input.FullName(), err)
+		return nil, nil, &DeliveryError{Class: ErrorClassRequest, URL: g.target + g.method, Err: err}
+	}
+
+	md, err := renderHeaders(g.metadata, msg)
+	if err != nil {
+		return nil, nil, &DeliveryError{Class: ErrorClassRequest, URL: g.target + g.method, Err: err}
+	}
+	md = append(md, headerValue{key: "kafka_key", value: sanitizeKey(msg.Key)})
+
+	if g.idempotency != nil {
+		header, err := g.idempotency.render(msg, value)
+		if err != nil {
+			return nil, nil, &DeliveryError{Class: ErrorClassRequest, URL: g.target + g.method, Err: err}
+		}
+		md = append(md, header)
+	}
+
+	return req, md, nil
+}
+
+func (g *grpcSink) Send(ctx context.Context, msg kafka.Message, value []byte) (*Delivery, error) {
+	req, md, err := g.buildRequest(msg, value)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]string, 0, len(md)*2)
+	for _, m := range md {
+		pairs = append(pairs, strings.ToLower(m.key), m.value)
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	res := dynamicpb.NewMessage(g.output)
+	var header metadata.MD
+	if err := g.conn.Invoke(ctx, g.method, req, res, grpc.Header(&header)); err != nil {
+		return nil, newGRPCDeliveryError(g.target+g.method, err)
+	}
+
+	body, err := protojson.Marshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode gRPC response: %w", err)
+	}
+
+	g.logr.Debug("got gRPC response", zap.String("method", g.method), zap.ByteString("body", body))
+	return &Delivery{Body: body, URL: g.target + g.method, Attempts: 1, Headers: header}, nil
+}
+
+// newGRPCDeliveryError classifies the status, codes without a response from the server are transport errors.
+func newGRPCDeliveryError(url string, err error) *DeliveryError {
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return &DeliveryError{Class: ErrorClassTransport, URL: url, Attempts: 1, Err: err}
+	default:
+		return &DeliveryError{
+			Class:      ErrorClassGRPC,
+			URL:        url,
+			Attempts:   1,
+			StatusCode: int(st.Code()),
+			Body:       []byte(st.Message()),
+			Err:        err,
+		}
+	}
+}
+
+// Inspect converts the value into the request message without calling the method,
+// secret metadata values are redacted.
+func (g *grpcSink) Inspect(msg kafka.Message, value []byte) (*Inspection, error) {
+	req, md, err := g.buildRequest(msg, value)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := protojson.Marshal(req)
+	if err != nil {
+		return nil, &DeliveryError{Class: ErrorClassRequest, Err: err}
+	}
+
+	inspection := &Inspection{
+		Method:  "GRPC",
+		URL:     g.target + g.method,
+		Headers: redactHeaders(md),
+	}
+	inspection.setBody(body)
+	return inspection, nil
+}
+
+func (g *grpcSink) Close() error {
+	return g.conn.Close()
+}
@@ -0,0 +1,267 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const ordersProto = `
+syntax = "proto3";
+package orders.v1;
+
+message CreateOrderRequest {
+  string id = 1;
+  int64 amount = 2;
+}
+
+message CreateOrderResponse {
+  string status = 1;
+}
+
+service OrderService {
+  rpc CreateOrder(CreateOrderRequest) returns (CreateOrderResponse);
+  rpc WatchOrders(CreateOrderRequest) returns (stream CreateOrderResponse);
+}
+`
+
+// writeDescriptorSet compiles ordersProto into a FileDescriptorSet file like protoc --descriptor_set_out.
+func writeDescriptorSet(t *testing.T) (string, protoreflect.ServiceDescriptor) {
+	t.Helper()
+
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{"orders.proto": ordersProto}),
+		},
+	}
+	files, err := compiler.Compile(context.Background(), "orders.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(files[0])},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "orders.pb")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path, files[0].Services().Get(0)
+}
+
+// startOrderService serves CreateOrder on an in-memory listener. The request id selects
+// the outcome and the received metadata is sent back in the response headers.
+func startOrderService(t *testing.T, service protoreflect.ServiceDescriptor) *bufconn.Listener {
+	t.Helper()
+
+	method := service.Methods().ByName("CreateOrder")
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		req := dynamicpb.NewMessage(method.Input())
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+
+		switch req.Get(method.Input().Fields().ByName("id")).String() {
+		case "invalid":
+			return status.Error(codes.InvalidArgument, "amount must be positive")
+		case "unavailable":
+			return status.Error(codes.Unavailable, "shutting down")
+		}
+
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		if err := stream.SetHeader(metadata.Pairs(
+			"kafka-key", firstValue(md.Get("kafka_key")),
+			"x-source", firstValue(md.Get("x-source")),
+		)); err != nil {
+			return err
+		}
+
+		res := dynamicpb.NewMessage(method.Output())
+		res.Set(method.Output().Fields().ByName("status"), protoreflect.ValueOfString("created"))
+		return stream.SendMsg(res)
+	}))
+
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis
+}
+
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func TestFindMethod(t *testing.T) {
+	descriptorSet, _ := writeDescriptorSet(t)
+
+	tests := []struct {
+		name          string
+		descriptorSet string
+		method        string
+		wantErr       bool
+	}{
+		{name: "unary method", descriptorSet: descriptorSet, method: "/orders.v1.OrderService/CreateOrder"},
+		{name: "without leading slash", descriptorSet: descriptorSet, method: "orders.v1.OrderService/CreateOrder"},
+		{name: "streaming method", descriptorSet: descriptorSet, method: "/orders.v1.OrderService/WatchOrders", wantErr: true},
+		{name: "unknown method", descriptorSet: descriptorSet, method: "/orders.v1.OrderService/CancelOrder", wantErr: true},
+		{name: "unknown service", descriptorSet: descriptorSet, method: "/orders.v1.PaymentService/CreateOrder", wantErr: true},
+		{name: "message is not a service", descriptorSet: descriptorSet, method: "/orders.v1.CreateOrderRequest/CreateOrder", wantErr: true},
+		{name: "invalid method format", descriptorSet: descriptorSet, method: "orders.v1.OrderService.CreateOrder", wantErr: true},
+		{name: "missing descriptor set", descriptorSet: filepath.Join(t.TempDir(), "missing.pb"), method: "/orders.v1.OrderService/CreateOrder", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, err := findMethod(tt.descriptorSet, tt.method)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findMethod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && method.Input().FullName() != "orders.v1.CreateOrderRequest" {
+				t.Errorf("findMethod() input = %s", method.Input().FullName())
+			}
+		})
+	}
+}
+
+func TestGRPCSinkSend(t *testing.T) {
+	descriptorSet, service := writeDescriptorSet(t)
+	lis := startOrderService(t, service)
+
+	sink, err := NewGRPCSink(config.GrpcSinkConfig{
+		Target:        "passthrough:///bufnet",
+		Method:        "/orders.v1.OrderService/CreateOrder",
+		DescriptorSet: descriptorSet,
+		Metadata:      config.HeaderSpecs{"X-Source: {{ .Topic }}"},
+		Insecure:      true,
+		Timeout:       time.Second,
+	}, config.IdempotencyConfig{}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewGRPCSink() error = %v", err)
+	}
+	// dial the in-memory listener instead of the network
+	sink.conn.Close()
+	sink.conn, err = grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	tests := []struct {
+		name       string
+		value      string
+		wantBody   string
+		wantClass  string
+		wantStatus int
+	}{
+		{
+			name:     "created",
+			value:    `{"id":"order-1","amount":"10"}`,
+			wantBody: `{"status":"created"}`,
+		},
+		{
+			name:      "value does not match the request message",
+			value:     `{"id":"order-1","unknown":true}`,
+			wantClass: ErrorClassRequest,
+		},
+		{
+			name:       "error status",
+			value:      `{"id":"invalid"}`,
+			wantClass:  ErrorClassGRPC,
+			wantStatus: int(codes.InvalidArgument),
+		},
+		{
+			name:      "unavailable",
+			value:     `{"id":"unavailable"}`,
+			wantClass: ErrorClassTransport,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := kafka.Message{Topic: "orders", Key: []byte("order-1")}
+			delivery, err := sink.Send(context.Background(), msg, []byte(tt.value))
+
+			if tt.wantClass != "" {
+				var deliveryErr *DeliveryError
+				if !errors.As(err, &deliveryErr) || deliveryErr.Class != tt.wantClass || deliveryErr.StatusCode != tt.wantStatus {
+					t.Errorf("Send() error = %v, want class %q and status %d", err, tt.wantClass, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			// protojson randomizes the whitespace
+			var body bytes.Buffer
+			if err := json.Compact(&body, delivery.Body); err != nil || body.String() != tt.wantBody {
+				t.Errorf("Send() body = %s, want %s", delivery.Body, tt.wantBody)
+			}
+			if firstValue(delivery.Headers["kafka-key"]) != "order-1" || firstValue(delivery.Headers["x-source"]) != "orders" {
+				t.Errorf("Send() headers = %v, want the metadata echoed back", delivery.Headers)
+			}
+		})
+	}
+}
+
+func TestNewGRPCTLSConfig(t *testing.T) {
+	invalidCA := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(invalidCA, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		conf    config.GrpcSinkConfig
+		wantErr bool
+	}{
+		{name: "system roots", conf: config.GrpcSinkConfig{ServerName: "orders.internal"}},
+		{name: "invalid CA file", conf: config.GrpcSinkConfig{CAFile: invalidCA}, wantErr: true},
+		{name: "missing CA file", conf: config.GrpcSinkConfig{CAFile: invalidCA + ".missing"}, wantErr: true},
+		{name: "cert without key", conf: config.GrpcSinkConfig{CertFile: "client.pem"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newGRPCTLSConfig(tt.conf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newGRPCTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.ServerName != tt.conf.ServerName {
+				t.Errorf("newGRPCTLSConfig() ServerName = %q, want %q", got.ServerName, tt.conf.ServerName)
+			}
+		})
+	}
+}
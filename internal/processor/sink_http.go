@@ -0,0 +1,393 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"go.uber.org/zap"
+)
+
+// httpSink sends the decoded value as the request body to the HTTP API.
+type httpSink struct {
+	http        *resty.Client
+	url         string
+	urlTemplate *template.Template
+	method      string
+	pathParam   *string
+	logr        *zap.Logger
+	headers     []httpHeader
+	idempotency *idempotency
+	criteria    *successCriteria
+	mapping     *headerMapping
+	encoder     *bodyEncoder
+	query       []config.QueryParam
+	maxURL      int
+	balancer    *endpointBalancer
+}
+
+// httpRequest is the HTTP request built from a message before it is sent.
+type httpRequest struct {
+	method  string
+	url     string
+	headers []headerValue
+	body    []byte
+}
+
+func NewHTTPSink(name string, conf *config.Config, logr *zap.Logger) (*httpSink, error) {
+	var urlTemplate *template.Template
+	if strings.Contains(conf.HttpApiUrl, "{{") {
+		tmpl, err := template.New("url").Funcs(templateFuncs()).Option("missingkey=zero").Parse(conf.HttpApiUrl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTTP API URL template: %w", err)
+		}
+		urlTemplate = tmpl
+	}
+
+	headers, err := parseHeaderSpecs(conf.HttpHeaders, logr)
+	if err != nil {
+		return nil, err
+	}
+
+	idempotency, err := newIdempotency(conf.Idempotency)
+	if err != nil {
+		return nil, err
+	}
+
+	criteria, err := newSuccessCriteria(conf.HttpSuccess)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, err := newHeaderMapping(conf.HttpKeyHeader, conf.HttpKafkaHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, err := newBodyEncoder(conf.HttpBody)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newHTTPClient(conf.HttpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	balancer, err := newEndpointBalancer(name, conf.HttpEndpoints, logr)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := parseQueryParams(conf.HttpQuery)
+	if err != nil {
+		return nil, err
+	}
+	maxURL := conf.HttpMaxURLLength
+	if maxURL == 0 {
+		maxURL = defaultMaxURLLength
+	}
+
+	// Set HTTP method, default to POST if not specified
+	method := "POST"
+	if conf.HttpMethod != nil {
+		method = strings.ToUpper(*conf.HttpMethod)
+		// Validate allowed HTTP methods
+		validMethods := map[string]bool{
+			"POST":   true,
+			"PUT":    true,
+			"PATCH":  true,
+			"DELETE": true,
+			"GET":    true,
+			"HEAD":   true,
+		}
+		if !validMethods[method] {
+			return nil, fmt.Errorf("invalid HTTP method: %s. Allowed methods: POST, PUT, PATCH, DELETE, GET, HEAD", method)
+		}
+	}
+
+	return &httpSink{
+		http:        client,
+		url:         conf.HttpApiUrl,
+		urlTemplate: urlTemplate,
+		method:      method,
+		pathParam:   conf.HttpPathParam,
+		headers:     headers,
+		idempotency: idempotency,
+		criteria:    criteria,
+		mapping:     mapping,
+		encoder:     encoder,
+		query:       query,
+		maxURL:      maxURL,
+		balancer:    balancer,
+		logr:        logr,
+	}, nil
+}
+
+// parseURL builds the final URL from the URL template and path parameter substitution if configured.
+// The URL template can use the message context, e.g. "http://api.com/v1/{{ .Topic }}/events",
+// the message values are path escaped.
+// For path parameter it validates and sanitizes the message key, URL-encodes it, and substitutes it into the base URL.
+// Returns error if key is empty after sanitization or placeholder is not found in URL.
+func (h *httpSink) parseURL(msg kafka.Message) (string, error) {
+	baseURL := h.url
+	if h.urlTemplate != nil {
+		var b strings.Builder
+		if err := h.urlTemplate.Execute(&b, newURLTemplateData(msg)); err != nil {
+			return "", fmt.Errorf("failed to render URL template: %w", err)
+		}
+		baseURL = b.String()
+	}
+
+	// If no path parameter is configured, return base URL
+	if h.pathParam == nil {
+		return baseURL, nil
+	}
+
+	// Sanitize the message key
+	sanitizedKey := sanitizeKey(msg.Key)
+	if sanitizedKey == "" {
+		err := fmt.Errorf("message key is empty after sanitization, cannot substitute path parameter %s", *h.pathParam)
+		return "", err
+	}
+
+	// URL-encode the sanitized key
+	encodedKey := escapePathValue(sanitizedKey)
+
+	// Substitute the path parameter
+	finalURL, err := substitutePathParam(baseURL, *h.pathParam, encodedKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to substitute path parameter: %w", err)
+	}
+
+	return finalURL, nil
+}
+
+// buildRequest renders the URL and headers for the decoded value.
+func (h *httpSink) buildRequest(msg kafka.Message, value []byte) (*httpRequest, error) {
+	req := &httpRequest{method: h.method}
+
+	// GET and HEAD have no body, the value is only used for the query parameters
+	var contentType, contentEncoding string
+	if !h.bodyless() {
+		body, bodyType, err := h.encoder.encode(msg, value)
+		if err != nil {
+			return nil, &DeliveryError{Class: ErrorClassRequest, Err: err}
+		}
+		req.body, contentType, contentEncoding = body, bodyType, h.encoder.contentEncoding()
+	}
+
+	headers, err := renderHeaders(h.headers, msg)
+	if err != nil {
+		return nil, &DeliveryError{Class: ErrorClassRequest, Err: err}
+	}
+
+	// the mapped Kafka headers go first so a producer cannot override the encoding
+	// or the configured headers, e.g. Authorization, and the encoding headers go
+	// before HTTP_HEADERS so they can be overridden by configuration
+	mapped, err := h.mapping.apply(msg)
+	if err != nil {
+		return nil, &DeliveryError{Class: ErrorClassRequest, Err: err}
+	}
+	if contentType != "" {
+		mapped = append(mapped, headerValue{key: "Content-Type", value: contentType})
+	}
+	if contentEncoding != "" {
+		mapped = append(mapped, headerValue{key: "Content-Encoding", value: contentEncoding})
+	}
+	req.headers = append(mapped, headers...)
+
+	// the idempotency header is set last so a Kafka header cannot override it
+	if h.idempotency != nil {
+		header, err := h.idempotency.render(msg, value)
+		if err != nil {
+			return nil, &DeliveryError{Class: ErrorClassRequest, Err: err}
+		}
+		req.headers = append(req.headers, header)
+	}
+
+	// Build final URL with path parameter substitution if configured
+	req.url, err = h.parseURL(msg)
+	if err != nil {
+		return nil, &DeliveryError{Class: ErrorClassRequest, Err: err}
+	}
+
+	req.url, err = addQuery(req.url, h.query, value)
+	if err != nil {
+		return nil, &DeliveryError{Class: ErrorClassRequest, Err: err}
+	}
+
+	return req, nil
+}
+
+// checkURL fails the message when the final URL, i.e. after the endpoint host is set, is too long.
+func (h *httpSink) checkURL(finalURL string) error {
+	if h.maxURL > 0 && len(finalURL) > h.maxURL {
+		err := fmt.Errorf("URL has %d characters, more than the maximum of %d", len(finalURL), h.maxURL)
+		return &DeliveryError{Class: ErrorClassRequest, URL: finalURL, Err: err}
+	}
+	return nil
+}
+
+func (h *httpSink) Send(ctx context.Context, msg kafka.Message, value []byte) (*Delivery, error) {
+	req, err := h.buildRequest(msg, value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set request body once, compressed after building so inspections show the readable body
+	var body []byte
+	if !h.bodyless() {
+		body, err = h.encoder.compress(req.body)
+		if err != nil {
+			return nil, &DeliveryError{Class: ErrorClassRequest, URL: req.url, Err: fmt.Errorf("failed to compress body: %w", err)}
+		}
+	}
+
+	if h.balancer == nil {
+		if err := h.checkURL(req.url); err != nil {
+			return nil, err
+		}
+		res, attempts, err := h.do(ctx, req, req.url, body)
+		return h.delivery(req.url, res, attempts, err)
+	}
+
+	// A transport error fails over to the next endpoint, a response is final whatever its status
+	var attempts int
+	var finalURL string
+	var res *resty.Response
+	for _, e := range h.balancer.order(msg) {
+		finalURL, err = e.resolve(req.url)
+		if err != nil {
+			return nil, &DeliveryError{Class: ErrorClassRequest, Err: err}
+		}
+		if err := h.checkURL(finalURL); err != nil {
+			return nil, err
+		}
+
+		var tries int
+		res, tries, err = h.do(ctx, req, finalURL, body)
+		attempts += tries
+		h.balancer.record(e, err != nil || res.StatusCode() >= 500)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+		h.logr.Warn("endpoint failed, trying the next one", zap.String("url", finalURL), zap.Error(err))
+	}
+
+	return h.delivery(finalURL, res, attempts, err)
+}
+
+// do sends the request to the final URL and returns the response with the number of attempts.
+func (h *httpSink) do(ctx context.Context, req *httpRequest, finalURL string, body []byte) (*resty.Response, int, error) {
+	r := h.http.NewRequest().SetContext(ctx)
+	for _, header := range req.headers {
+		r.SetHeader(header.key, header.value)
+	}
+	if !h.bodyless() {
+		r.SetBody(body)
+	}
+
+	// Execute HTTP request based on configured method
+	var res *resty.Response
+	var err error
+	switch h.method {
+	case "POST":
+		res, err = r.Post(finalURL)
+	case "PUT":
+		res, err = r.Put(finalURL)
+	case "PATCH":
+		res, err = r.Patch(finalURL)
+	case "DELETE":
+		res, err = r.Delete(finalURL)
+	case "GET":
+		res, err = r.Get(finalURL)
+	case "HEAD":
+		res, err = r.Head(finalURL)
+	default:
+		return nil, 0, fmt.Errorf("unsupported HTTP method: %s", h.method)
+	}
+
+	return res, r.Attempt, err
+}
+
+// delivery checks the response against the success criteria.
+func (h *httpSink) delivery(finalURL string, res *resty.Response, attempts int, err error) (*Delivery, error) {
+	if err != nil {
+		return nil, &DeliveryError{Class: ErrorClassTransport, URL: finalURL, Attempts: attempts, Err: err}
+	}
+
+	if !h.criteria.statusOK(res.StatusCode()) {
+		return nil, &DeliveryError{
+			Class:      ErrorClassHTTP,
+			URL:        finalURL,
+			Attempts:   attempts,
+			StatusCode: res.StatusCode(),
+			Body:       res.Body(),
+			Err:        fmt.Errorf("error from http with status code '%d': %s", res.StatusCode(), string(res.Body())),
+		}
+	}
+
+	if err := h.criteria.check(res.Header(), res.Body()); err != nil {
+		return nil, &DeliveryError{
+			Class:      ErrorClassResponse,
+			URL:        finalURL,
+			Attempts:   attempts,
+			StatusCode: res.StatusCode(),
+			Body:       res.Body(),
+			Err:        fmt.Errorf("unsuccessful response with status code '%d': %w", res.StatusCode(), err),
+		}
+	}
+
+	h.logr.Debug("got " + res.Status() + " with body " + string(res.Body()))
+	return &Delivery{
+		Body:       res.Body(),
+		StatusCode: res.StatusCode(),
+		URL:        finalURL,
+		Attempts:   attempts,
+		Headers:    res.Header(),
+	}, nil
+}
+
+// Inspect builds the request for the message without sending it, secret header values are redacted.
+func (h *httpSink) Inspect(msg kafka.Message, value []byte) (*Inspection, error) {
+	req, err := h.buildRequest(msg, value)
+	if err != nil {
+		return nil, err
+	}
+
+	// the first endpoint is the one the message would be sent to, peeking keeps the rotation unchanged
+	if h.balancer != nil {
+		req.url, err = h.balancer.peek(msg).resolve(req.url)
+		if err != nil {
+			return nil, &DeliveryError{Class: ErrorClassRequest, Err: err}
+		}
+	}
+	if err := h.checkURL(req.url); err != nil {
+		return nil, err
+	}
+
+	inspection := &Inspection{
+		Method:  req.method,
+		URL:     req.url,
+		Headers: redactHeaders(req.headers),
+	}
+	inspection.setBody(req.body)
+	return inspection, nil
+}
+
+// bodyless tells whether the method sends no body.
+func (h *httpSink) bodyless() bool {
+	return h.method == "GET" || h.method == "HEAD"
+}
+
+// Close releases the idle connections, e.g. of a sink replaced on config reload.
+func (h *httpSink) Close() error {
+	h.encoder.close()
+	h.http.GetClient().CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,397 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWriterSink(t *testing.T) {
+	msg := kafka.Message{
+		Topic:     "orders",
+		Partition: 1,
+		Offset:    7,
+		Key:       []byte("key"),
+		Headers:   []kafka.Header{{Key: "trace", Value: []byte("abc")}},
+	}
+
+	tests := []struct {
+		name      string
+		envelope  bool
+		value     []byte
+		wantLine  string
+		wantClass string
+	}{
+		{
+			name:     "compact json value",
+			value:    []byte("{\n  \"id\": 1\n}"),
+			wantLine: `{"id":1}` + "\n",
+		},
+		{
+			name:     "json value with envelope",
+			envelope: true,
+			value:    []byte(`{"id":1}`),
+			wantLine: `{"topic":"orders","partition":1,"offset":7,"key":"key","headers":{"trace":"abc"},"value":{"id":1}}` + "\n",
+		},
+		{
+			name:     "text value with envelope",
+			envelope: true,
+			value:    []byte("plain"),
+			wantLine: `{"topic":"orders","partition":1,"offset":7,"key":"key","headers":{"trace":"abc"},"value_text":"plain"}` + "\n",
+		},
+		{
+			name:      "text value without envelope",
+			value:     []byte("plain"),
+			wantClass: ErrorClassRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			sink := &writerSink{out: &out, name: "test", envelope: tt.envelope}
+
+			delivery, err := sink.Send(context.Background(), msg, tt.value)
+
+			var deliveryErr *DeliveryError
+			if tt.wantClass != "" {
+				if !errors.As(err, &deliveryErr) || deliveryErr.Class != tt.wantClass {
+					t.Errorf("Send() error = %v, want class %q", err, tt.wantClass)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if out.String() != tt.wantLine {
+				t.Errorf("Send() wrote %q, want %q", out.String(), tt.wantLine)
+			}
+			if string(delivery.Body)+"\n" != tt.wantLine {
+				t.Errorf("Send() delivery body = %q, want the written line", delivery.Body)
+			}
+		})
+	}
+}
+
+func TestNewGRPCDeliveryError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantClass string
+		wantCode  int
+	}{
+		{
+			name:      "unavailable is transport",
+			err:       status.Error(codes.Unavailable, "connection refused"),
+			wantClass: ErrorClassTransport,
+		},
+		{
+			name:      "invalid argument keeps the code",
+			err:       status.Error(codes.InvalidArgument, "missing id"),
+			wantClass: ErrorClassGRPC,
+			wantCode:  int(codes.InvalidArgument),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newGRPCDeliveryError("orders:9090/orders.v1.OrderService/CreateOrder", tt.err)
+			if got.Class != tt.wantClass || got.StatusCode != tt.wantCode {
+				t.Errorf("newGRPCDeliveryError() = %s/%d, want %s/%d", got.Class, got.StatusCode, tt.wantClass, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestSetSink(t *testing.T) {
+	var previous, next bytes.Buffer
+	processor := &messageProcessor{sink: &writerSink{out: &previous, closer: &closeRecorder{}, name: "previous"}}
+	msg := kafka.Message{Topic: "orders", Value: []byte(`{"id":1}`)}
+
+	if err := processor.Process(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	closer := processor.sink.(*writerSink).closer.(*closeRecorder)
+	if err := processor.SetSink(&writerSink{out: &next, name: "next"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.Process(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if !closer.closed {
+		t.Error("SetSink() should close the previous sink")
+	}
+	if previous.Len() == 0 || next.Len() == 0 || previous.String() != next.String() {
+		t.Errorf("previous sink wrote %q, next sink wrote %q, want one message each", previous.String(), next.String())
+	}
+}
+
+type closeRecorder struct {
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestHTTPSinkQuery(t *testing.T) {
+	type received struct {
+		method string
+		query  string
+		body   string
+	}
+	var got received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = received{method: r.Method, query: r.URL.RawQuery, body: string(body)}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		method    string
+		query     []string
+		maxURL    int
+		value     string
+		want      received
+		wantClass string
+	}{
+		{
+			name:   "get with mapped fields",
+			method: "get",
+			query:  []string{"id: order.id", "tag: order.tags", "missing: order.missing"},
+			value:  `{"order":{"id":42,"tags":["a b","c&d"]}}`,
+			want:   received{method: "GET", query: "source=kafka&note=a%20b&id=42&tag=a+b&tag=c%26d"},
+		},
+		{
+			name:   "missing fields keep the url",
+			method: "GET",
+			query:  []string{"id: order.id"},
+			value:  `{"order":{}}`,
+			want:   received{method: "GET", query: "source=kafka&note=a%20b"},
+		},
+		{
+			name:   "head",
+			method: "HEAD",
+			query:  []string{"id: order.id"},
+			value:  `{"order":{"id":"o-1"}}`,
+			want:   received{method: "HEAD", query: "source=kafka&note=a%20b&id=o-1"},
+		},
+		{
+			name:   "post keeps the body",
+			method: "POST",
+			query:  []string{"id: order.id"},
+			value:  `{"order":{"id":1}}`,
+			want:   received{method: "POST", query: "source=kafka&note=a%20b&id=1", body: `{"order":{"id":1}}`},
+		},
+		{
+			name:      "url too long",
+			method:    "GET",
+			query:     []string{"note: note"},
+			maxURL:    64,
+			value:     `{"note":"` + strings.Repeat("x", 64) + `"}`,
+			wantClass: ErrorClassRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = received{}
+			sink, err := NewHTTPSink("orders", &config.Config{
+				HttpApiUrl:       server.URL + "/trigger?source=kafka&note=a%20b",
+				HttpMethod:       &tt.method,
+				HttpQuery:        tt.query,
+				HttpMaxURLLength: tt.maxURL,
+			}, zap.NewNop())
+			if err != nil {
+				t.Fatalf("NewHTTPSink() error = %v", err)
+			}
+			defer sink.Close()
+
+			_, err = sink.Send(context.Background(), kafka.Message{Topic: "orders"}, []byte(tt.value))
+			var deliveryErr *DeliveryError
+			if tt.wantClass != "" {
+				if !errors.As(err, &deliveryErr) || deliveryErr.Class != tt.wantClass {
+					t.Fatalf("Send() error = %v, want class %s", err, tt.wantClass)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("server received %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPClientTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-body" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		conf      config.HttpClientConfig
+		wantClass string
+	}{
+		{
+			name:      "total timeout",
+			path:      "/slow-body",
+			conf:      config.HttpClientConfig{Timeout: 50 * time.Millisecond},
+			wantClass: ErrorClassTransport,
+		},
+		{
+			name:      "response header timeout",
+			path:      "/slow-header",
+			conf:      config.HttpClientConfig{ResponseHeaderTimeout: 50 * time.Millisecond},
+			wantClass: ErrorClassTransport,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink, err := NewHTTPSink("orders", &config.Config{HttpApiUrl: server.URL + tt.path, HttpClient: tt.conf}, zap.NewNop())
+			if err != nil {
+				t.Fatalf("NewHTTPSink() error = %v", err)
+			}
+			defer sink.Close()
+
+			start := time.Now()
+			_, err = sink.Send(context.Background(), kafka.Message{}, []byte(`{"id":1}`))
+			var deliveryErr *DeliveryError
+			if !errors.As(err, &deliveryErr) || deliveryErr.Class != tt.wantClass {
+				t.Fatalf("Send() error = %v, want class %s", err, tt.wantClass)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Send() took %v, want the timeout to stop it", elapsed)
+			}
+		})
+	}
+}
+
+func TestHTTPSinkFailover(t *testing.T) {
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer live.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	sink, err := NewHTTPSink("orders", &config.Config{
+		HttpApiUrl: "/v1/orders",
+		HttpEndpoints: config.HttpEndpointsConfig{
+			URLs:       []string{dead.URL, live.URL},
+			EjectAfter: 1,
+			EjectFor:   time.Minute,
+		},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewHTTPSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	// the first message starts on the dead endpoint and fails over, which also ejects it
+	for i := 0; i < 3; i++ {
+		delivery, err := sink.Send(context.Background(), kafka.Message{}, []byte(`{"id":1}`))
+		if err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		if delivery.URL != live.URL+"/v1/orders" || string(delivery.Body) != "/v1/orders" {
+			t.Errorf("Send() delivered to %s with body %s, want %s/v1/orders", delivery.URL, delivery.Body, live.URL)
+		}
+	}
+
+	if got := endpointEjectionsTotal.Value("orders", strings.TrimPrefix(dead.URL, "http://")); got != 1 {
+		t.Errorf("ejections = %v, want 1", got)
+	}
+}
+
+func TestHTTPSinkEndpointURLLength(t *testing.T) {
+	// the path alone fits, the URL resolved against the endpoint does not
+	sink, err := NewHTTPSink("orders", &config.Config{
+		HttpApiUrl:       "/v1/orders",
+		HttpMaxURLLength: 32,
+		HttpEndpoints:    config.HttpEndpointsConfig{URLs: []string{"http://orders-api.internal:8080"}},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewHTTPSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	var deliveryErr *DeliveryError
+	if _, err := sink.Inspect(kafka.Message{}, []byte(`{"id":1}`)); !errors.As(err, &deliveryErr) || deliveryErr.Class != ErrorClassRequest {
+		t.Errorf("Inspect() error = %v, want class %s", err, ErrorClassRequest)
+	}
+	if _, err := sink.Send(context.Background(), kafka.Message{}, []byte(`{"id":1}`)); !errors.As(err, &deliveryErr) || deliveryErr.Class != ErrorClassRequest {
+		t.Errorf("Send() error = %v, want class %s", err, ErrorClassRequest)
+	}
+}
+
+func TestHTTPSinkHeaderPrecedence(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	sink, err := NewHTTPSink("orders", &config.Config{
+		HttpApiUrl:  server.URL,
+		HttpHeaders: config.HeaderSpecs{"Authorization: Bearer configured"},
+		HttpBody:    config.HttpBodyConfig{Compression: CompressionGzip},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewHTTPSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	msg := kafka.Message{
+		Key: []byte("order-1"),
+		Headers: []kafka.Header{
+			{Key: "Authorization", Value: []byte("Bearer producer")},
+			{Key: "Content-Encoding", Value: []byte("identity")},
+			{Key: "Content-Type", Value: []byte("text/plain")},
+			{Key: "X-Trace", Value: []byte("abc")},
+		},
+	}
+	if _, err := sink.Send(context.Background(), msg, []byte(`{"id":1}`)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	want := map[string]string{
+		"Authorization":    "Bearer configured",
+		"Content-Encoding": "gzip",
+		"Content-Type":     "application/json",
+		"X-Trace":          "abc",
+		"Kafka_key":        "order-1",
+	}
+	for name, value := range want {
+		if got.Get(name) != value {
+			t.Errorf("header %s = %q, want %q", name, got.Get(name), value)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+// SuccessRecord is the value written to the success topic when SUCCESS_RECORD is enabled,
+// so downstream consumers can correlate the response with the consumed message.
+// Bodies are kept as JSON when valid, otherwise as text in the *_text field.
+type SuccessRecord struct {
+	Topic           string            `json:"topic"`
+	Partition       int               `json:"partition"`
+	Offset          int64             `json:"offset"`
+	Timestamp       time.Time         `json:"timestamp"`
+	URL             string            `json:"url"`
+	StatusCode      int               `json:"status_code,omitempty"`
+	Attempts        int               `json:"attempts"`
+	LatencyMs       float64           `json:"latency_ms"`
+	DeliveredAt     time.Time         `json:"delivered_at"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    json.RawMessage   `json:"response_body,omitempty"`
+	ResponseText    string            `json:"response_body_text,omitempty"`
+	RequestBody     json.RawMessage   `json:"request_body,omitempty"`
+	RequestText     string            `json:"request_body_text,omitempty"`
+}
+
+// successFormat builds the success topic message from the delivery.
+type successFormat struct {
+	conf           config.SuccessConfig
+	headerMappings []config.ResponseMapping
+	valueMappings  []config.ResponseMapping
+	key            *config.MappingSource
+	now            func() time.Time
+}
+
+func newSuccessFormat(conf config.SuccessConfig) (*successFormat, error) {
+	f := &successFormat{conf: conf, now: time.Now}
+
+	for _, spec := range conf.HeaderMappings {
+		mapping, err := config.ParseResponseMapping(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid success header mapping: %w", err)
+		}
+		f.headerMappings = append(f.headerMappings, mapping)
+	}
+	for _, spec := range conf.ValueMappings {
+		mapping, err := config.ParseResponseMapping(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid success value mapping: %w", err)
+		}
+		f.valueMappings = append(f.valueMappings, mapping)
+	}
+	if conf.Key != "" {
+		key, err := config.ParseMappingSource(conf.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid success key: %w", err)
+		}
+		f.key = &key
+	}
+
+	return f, nil
+}
+
+// message returns the success message for the delivered message, value is the decoded request body.
+func (f *successFormat) message(msg kafka.Message, value []byte, delivery *Delivery, latency time.Duration) (kafka.Message, error) {
+	out := kafka.Message{Key: msg.Key, Value: delivery.Body}
+	if f.conf.PropagateHeaders {
+		out.Headers = append([]kafka.Header{}, msg.Headers...)
+	}
+
+	res := &response{delivery: delivery}
+	switch {
+	case f.conf.Record:
+		value, err := f.record(msg, value, delivery, latency)
+		if err != nil {
+			return kafka.Message{}, err
+		}
+		out.Value = value
+	case len(f.valueMappings) > 0:
+		reshaped := map[string]interface{}{}
+		for _, mapping := range f.valueMappings {
+			if field, ok := res.lookup(mapping.Source); ok {
+				reshaped[mapping.Name] = field
+			}
+		}
+		value, err := json.Marshal(reshaped)
+		if err != nil {
+			return kafka.Message{}, fmt.Errorf("failed to encode mapped success value: %w", err)
+		}
+		out.Value = value
+	}
+
+	for _, mapping := range f.headerMappings {
+		if header, ok := res.lookupString(mapping.Source); ok {
+			out.Headers = append(out.Headers, kafka.Header{Key: mapping.Name, Value: []byte(header)})
+		}
+	}
+
+	if f.key != nil {
+		if key, ok := res.lookupString(*f.key); ok {
+			out.Key = []byte(key)
+		}
+	}
+
+	return out, nil
+}
+
+// record encodes the SuccessRecord of the delivery.
+func (f *successFormat) record(msg kafka.Message, value []byte, delivery *Delivery, latency time.Duration) ([]byte, error) {
+	record := SuccessRecord{
+		Topic:       msg.Topic,
+		Partition:   msg.Partition,
+		Offset:      msg.Offset,
+		Timestamp:   msg.Time,
+		URL:         delivery.URL,
+		StatusCode:  delivery.StatusCode,
+		Attempts:    delivery.Attempts,
+		LatencyMs:   float64(latency.Microseconds()) / 1000,
+		DeliveredAt: f.now().UTC(),
+	}
+
+	for _, name := range f.conf.ResponseHeaders {
+		if values := lookupHeader(delivery.Headers, name); len(values) > 0 {
+			if record.ResponseHeaders == nil {
+				record.ResponseHeaders = map[string]string{}
+			}
+			record.ResponseHeaders[name] = strings.Join(values, ", ")
+		}
+	}
+
+	if json.Valid(delivery.Body) {
+		record.ResponseBody = delivery.Body
+	} else {
+		record.ResponseText = string(delivery.Body)
+	}
+
+	if f.conf.IncludeRequestBody {
+		if json.Valid(value) {
+			record.RequestBody = value
+		} else {
+			record.RequestText = string(value)
+		}
+	}
+
+	content, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode success record: %w", err)
+	}
+	return content, nil
+}
+
+// lookupHeader returns the values of the header ignoring the case of the name,
+// gRPC metadata keys are lower case while HTTP header keys are canonicalized.
+func lookupHeader(headers map[string][]string, name string) []string {
+	for key, values := range headers {
+		if strings.EqualFold(key, name) {
+			return values
+		}
+	}
+	return nil
+}
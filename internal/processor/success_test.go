@@ -0,0 +1,120 @@
+package processor
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/urbanindo/go-kafka-http-sink/config"
+)
+
+func TestSuccessMessage(t *testing.T) {
+	msgTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	msg := kafka.Message{
+		Topic:     "orders",
+		Partition: 2,
+		Offset:    42,
+		Time:      msgTime,
+		Key:       []byte("order-1"),
+		Headers:   []kafka.Header{{Key: "trace", Value: []byte("abc")}},
+	}
+	delivery := &Delivery{
+		Body:       []byte(`{"id":"r-1"}`),
+		StatusCode: http.StatusCreated,
+		URL:        "http://api/orders",
+		Attempts:   1,
+		Headers:    http.Header{"Location": {"/orders/r-1"}, "X-Request-Id": {"req-1"}},
+	}
+
+	tests := []struct {
+		name        string
+		conf        config.SuccessConfig
+		delivery    *Delivery
+		wantKey     string
+		wantValue   string
+		wantHeaders []kafka.Header
+	}{
+		{
+			name:      "raw response body",
+			conf:      config.SuccessConfig{},
+			delivery:  delivery,
+			wantValue: `{"id":"r-1"}`,
+		},
+		{
+			name:        "raw response body with propagated headers",
+			conf:        config.SuccessConfig{PropagateHeaders: true},
+			delivery:    delivery,
+			wantValue:   `{"id":"r-1"}`,
+			wantHeaders: []kafka.Header{{Key: "trace", Value: []byte("abc")}},
+		},
+		{
+			name:     "record",
+			conf:     config.SuccessConfig{Record: true, ResponseHeaders: []string{"location", "Etag"}, IncludeRequestBody: true},
+			delivery: delivery,
+			wantValue: `{"topic":"orders","partition":2,"offset":42,"timestamp":"2024-01-02T03:04:05Z","url":"http://api/orders",` +
+				`"status_code":201,"attempts":1,"latency_ms":12.5,"delivered_at":"2024-01-02T03:04:06Z",` +
+				`"response_headers":{"location":"/orders/r-1"},"response_body":{"id":"r-1"},"request_body":{"order":1}}`,
+		},
+		{
+			name: "mapped headers, value and key",
+			conf: config.SuccessConfig{
+				HeaderMappings: []string{"order-id: body.data.id", "location: header.location", "missing: body.data.missing"},
+				ValueMappings:  []string{"id: body.data.id", "total: body.data.total", "items: body.data.items", "request: header.X-Request-Id"},
+				Key:            "body.data.id",
+			},
+			delivery: &Delivery{
+				Body:    []byte(`{"data":{"id":"o-9","total":12.50,"items":[1,2]}}`),
+				Headers: http.Header{"Location": {"/orders/o-9"}, "X-Request-Id": {"req-1"}},
+			},
+			wantKey:   "o-9",
+			wantValue: `{"id":"o-9","items":[1,2],"request":"req-1","total":12.50}`,
+			wantHeaders: []kafka.Header{
+				{Key: "order-id", Value: []byte("o-9")},
+				{Key: "location", Value: []byte("/orders/o-9")},
+			},
+		},
+		{
+			name:      "key source not found keeps the original key",
+			conf:      config.SuccessConfig{Key: "body.data.id"},
+			delivery:  &Delivery{Body: []byte("OK")},
+			wantValue: "OK",
+		},
+		{
+			name:     "record with text response",
+			conf:     config.SuccessConfig{Record: true},
+			delivery: &Delivery{Body: []byte("OK"), StatusCode: http.StatusOK, URL: "http://api/orders", Attempts: 2},
+			wantValue: `{"topic":"orders","partition":2,"offset":42,"timestamp":"2024-01-02T03:04:05Z","url":"http://api/orders",` +
+				`"status_code":200,"attempts":2,"latency_ms":12.5,"delivered_at":"2024-01-02T03:04:06Z","response_body_text":"OK"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := newSuccessFormat(tt.conf)
+			if err != nil {
+				t.Fatalf("newSuccessFormat() error = %v", err)
+			}
+			format.now = func() time.Time { return msgTime.Add(time.Second) }
+
+			got, err := format.message(msg, []byte(`{"order":1}`), tt.delivery, 12500*time.Microsecond)
+			if err != nil {
+				t.Fatalf("message() error = %v", err)
+			}
+			wantKey := tt.wantKey
+			if wantKey == "" {
+				wantKey = "order-1"
+			}
+			if string(got.Key) != wantKey {
+				t.Errorf("key = %q, want %q", got.Key, wantKey)
+			}
+			if string(got.Value) != tt.wantValue {
+				t.Errorf("value = %s, want %s", got.Value, tt.wantValue)
+			}
+			if !reflect.DeepEqual(got.Headers, tt.wantHeaders) {
+				t.Errorf("headers = %v, want %v", got.Headers, tt.wantHeaders)
+			}
+		})
+	}
+}
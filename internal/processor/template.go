@@ -0,0 +1,154 @@
+package processor
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// templateData is the per-message context available to header value and URL templates.
+// Example: "X-Source: {{ .Topic }}/{{ .Partition }}" or "X-User: {{ .Key }}"
+type templateData struct {
+	Key       string
+	Topic     string
+	Partition int
+	Offset    int64
+	Headers   map[string]string
+}
+
+func newTemplateData(msg kafka.Message) templateData {
+	headers := make(map[string]string, len(msg.Headers))
+	for _, header := range msg.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+
+	return templateData{
+		Key:       sanitizeKey(msg.Key),
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Headers:   headers,
+	}
+}
+
+// newURLTemplateData is the template data with the message values path escaped, so a
+// key or header containing "/", "?" or ".." cannot change the path or the query of the URL.
+func newURLTemplateData(msg kafka.Message) templateData {
+	data := newTemplateData(msg)
+	data.Key = escapePathValue(data.Key)
+	data.Topic = escapePathValue(data.Topic)
+	for key, value := range data.Headers {
+		data.Headers[key] = escapePathValue(value)
+	}
+	return data
+}
+
+// escapePathValue escapes the value as a single path segment, the "." and ".."
+// dot segments are escaped too since they would be resolved by the server.
+func escapePathValue(value string) string {
+	if value == "." || value == ".." {
+		return strings.ReplaceAll(value, ".", "%2E")
+	}
+	return url.PathEscape(value)
+}
+
+// cachedFile is the content of a template file, read again when the file changes.
+type cachedFile struct {
+	modTime time.Time
+	size    int64
+	value   string
+}
+
+// templateFuncs returns the functions available to header and URL templates,
+// pathEscape and queryEscape escape the values read from env or file for a URL.
+// File contents are cached until the file modification time or size changes, so a
+// rotated secret is picked up. Trailing line breaks are stripped since Kubernetes
+// secrets mounted from files usually end with a newline.
+func templateFuncs() template.FuncMap {
+	var files sync.Map
+
+	return template.FuncMap{
+		"pathEscape":  escapePathValue,
+		"queryEscape": url.QueryEscape,
+		"env": func(name string) (string, error) {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				return "", fmt.Errorf("environment variable %q is not set", name)
+			}
+			return value, nil
+		},
+		"file": func(path string) (string, error) {
+			// Stat follows the symlinks Kubernetes swaps when it updates a mounted secret
+			info, err := os.Stat(path)
+			if err != nil {
+				return "", fmt.Errorf("failed to read template file: %w", err)
+			}
+			if cached, ok := files.Load(path); ok {
+				if file := cached.(cachedFile); file.modTime.Equal(info.ModTime()) && file.size == info.Size() {
+					return file.value, nil
+				}
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("failed to read template file: %w", err)
+			}
+
+			value := strings.TrimRight(string(content), "\r\n")
+			files.Store(path, cachedFile{modTime: info.ModTime(), size: info.Size(), value: value})
+			return value, nil
+		},
+	}
+}
+
+// usesAnyFunc reports whether the template tree calls any of the given functions.
+func usesAnyFunc(node parse.Node, names ...string) bool {
+	switch n := node.(type) {
+	case *parse.IdentifierNode:
+		for _, name := range names {
+			if n.Ident == name {
+				return true
+			}
+		}
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			if usesAnyFunc(child, names...) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return usesAnyFunc(n.Pipe, names...)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if usesAnyFunc(cmd, names...) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if usesAnyFunc(arg, names...) {
+				return true
+			}
+		}
+	case *parse.IfNode:
+		return usesAnyFunc(n.Pipe, names...) || usesAnyFunc(n.List, names...) || usesAnyFunc(n.ElseList, names...)
+	case *parse.RangeNode:
+		return usesAnyFunc(n.Pipe, names...) || usesAnyFunc(n.List, names...) || usesAnyFunc(n.ElseList, names...)
+	case *parse.WithNode:
+		return usesAnyFunc(n.Pipe, names...) || usesAnyFunc(n.List, names...) || usesAnyFunc(n.ElseList, names...)
+	}
+	return false
+}